@@ -0,0 +1,170 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewCachedHealthChecker returns a health checker that executes all the health checks of next every
+// interval in a background goroutine, once started, and serves the last results. Results older than
+// maxAge are reported KO, so that a stuck scheduler is visible: maxAge should exceed the interval plus the
+// duration of the health checks. A non-positive interval is replaced by defaultCacheInterval, and a maxAge
+// shorter than the interval by twice the interval.
+func NewCachedHealthChecker(next HealthChecker, interval, maxAge time.Duration) *CachedHealthChecker {
+	if interval <= 0 {
+		interval = defaultCacheInterval
+	}
+	if maxAge < interval {
+		maxAge = 2 * interval
+	}
+
+	return &CachedHealthChecker{
+		next:     next,
+		interval: interval,
+		maxAge:   maxAge,
+	}
+}
+
+// defaultCacheInterval is the interval of the cached health checks when the given one is not positive.
+const defaultCacheInterval = time.Minute
+
+// CachedHealthChecker is a HealthChecker that serves cached results. Only the health check name "",
+// i.e. all the health checks, is cached: the other names are forwarded to the underlying health checker.
+type CachedHealthChecker struct {
+	next     HealthChecker
+	interval time.Duration
+	maxAge   time.Duration
+
+	mutex      sync.RWMutex
+	report     json.RawMessage
+	err        error
+	computedAt time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start starts executing the health checks in the background. The first execution is immediate. Starting
+// a started health checker has no effect.
+func (c *CachedHealthChecker) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.cancel != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	c.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		var ticker = time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			c.update(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}(c.done)
+}
+
+// Stop stops the background execution of the health checks, and waits for the running one to return.
+// The cached results are still served, and eventually reported as stale. The health checker can be
+// started again.
+func (c *CachedHealthChecker) Stop() {
+	c.mutex.Lock()
+	var cancel, done = c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// LastUpdate returns the time at which the cached results were computed, or the zero time if the health
+// checks have not been executed yet.
+func (c *CachedHealthChecker) LastUpdate() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.computedAt
+}
+
+func (c *CachedHealthChecker) update(ctx context.Context) {
+	var report, err = c.next.HealthCheck(ctx, "")
+	if ctx.Err() != nil {
+		// Stopped while checking: keep the previous results.
+		return
+	}
+
+	c.mutex.Lock()
+	c.report, c.err, c.computedAt = report, err, time.Now()
+	c.mutex.Unlock()
+}
+
+// HealthCheck returns the cached report for the health check name "", and executes the health check
+// otherwise.
+func (c *CachedHealthChecker) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	if name != "" {
+		return c.next.HealthCheck(ctx, name)
+	}
+
+	c.mutex.RLock()
+	var report, err, computedAt = c.report, c.err, c.computedAt
+	c.mutex.RUnlock()
+
+	switch {
+	case computedAt.IsZero():
+		return json.MarshalIndent([]CheckResult{{Name: "cache", Status: KO, Error: "health checks not executed yet"}}, "", "  ")
+	case err != nil:
+		return nil, err
+	case time.Since(computedAt) > c.maxAge:
+		return staleReport(report, computedAt)
+	default:
+		return report, nil
+	}
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (c *CachedHealthChecker) HealthCheckNames() []string {
+	return healthCheckNames(c.next)
+}
+
+// Check returns the cached results for the health check name "", and executes the health check otherwise.
+func (c *CachedHealthChecker) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	if name != "" {
+		return check(ctx, c.next, name)
+	}
+
+	var report, err = c.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReport(report)
+}
+
+// staleReport returns the report with all its checks KO.
+func staleReport(report json.RawMessage, computedAt time.Time) (json.RawMessage, error) {
+	var results, err = decodeReport(report)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Status = KO
+		results[i].Error = fmt.Sprintf("stale health check result computed at %s", computedAt.Format(time.RFC3339))
+	}
+	return encodeReport(report, results)
+}
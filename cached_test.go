@@ -0,0 +1,211 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachedHealthChecker(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		c       = NewCachedHealthChecker(mockHealthChecker, time.Hour, time.Hour)
+		updated = make(chan struct{}, 1)
+	)
+
+	// Not executed yet.
+	var results, err = c.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.True(t, c.LastUpdate().IsZero())
+
+	// The first execution is immediate, the next one in an hour.
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		updated <- struct{}{}
+		return okReport, nil
+	}).Times(1)
+	c.Start()
+	<-updated
+	waitForUpdate(t, c)
+	c.Stop()
+
+	// The cached results are served without executing the health checks.
+	var report json.RawMessage
+	report, err = c.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, okReport, report)
+	assert.False(t, c.LastUpdate().IsZero())
+
+	// The other health check names are executed.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "ping").Return(koReport, nil).Times(1)
+	results, err = c.Check(context.Background(), "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+}
+
+func TestCachedHealthCheckerInterval(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		c       = NewCachedHealthChecker(mockHealthChecker, 10*time.Millisecond, time.Hour)
+		updated = make(chan struct{}, 3)
+	)
+
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+		return koReport, nil
+	}).MinTimes(3)
+
+	c.Start()
+	for i := 0; i < 3; i++ {
+		<-updated
+	}
+	waitForUpdate(t, c)
+	c.Stop()
+
+	var results, err = c.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "fail", results[0].Error)
+}
+
+func TestCachedHealthCheckerStale(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		c              = NewCachedHealthChecker(mockHealthChecker, 200*time.Millisecond, 500*time.Millisecond)
+		updated        = make(chan struct{}, 1)
+		registryReport = json.RawMessage(`{"status": "OK", "modules": {"redis": [{"name": "ping", "status": "OK"}]}}`)
+	)
+
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		updated <- struct{}{}
+		return registryReport, nil
+	}).Times(1)
+	c.Start()
+	<-updated
+	waitForUpdate(t, c)
+	c.Stop()
+
+	var results, err = c.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+
+	// Stale results are KO, and keep the shape of the report.
+	assert.Eventually(t, func() bool { return time.Since(c.LastUpdate()) > 500*time.Millisecond }, 2*time.Second, 10*time.Millisecond)
+	var jsonReport json.RawMessage
+	jsonReport, err = c.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var report Report
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, KO, report.Status)
+
+	var r = report.Modules["redis"][0]
+	assert.Equal(t, "ping", r.Name)
+	assert.Equal(t, KO, r.Status)
+	assert.Contains(t, r.Error, "stale")
+}
+
+func TestCachedHealthCheckerError(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		c       = NewCachedHealthChecker(mockHealthChecker, time.Hour, time.Hour)
+		updated = make(chan struct{}, 1)
+		hcErr   = fmt.Errorf("fail")
+	)
+
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		updated <- struct{}{}
+		return nil, hcErr
+	}).Times(1)
+	c.Start()
+	<-updated
+	waitForUpdate(t, c)
+	c.Stop()
+
+	var report, err = c.HealthCheck(context.Background(), "")
+	assert.Equal(t, hcErr, err)
+	assert.Nil(t, report)
+}
+
+func TestCachedHealthCheckerStartStop(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	// Invalid durations are replaced by defaults: the health checks are not executed in a loop, and the
+	// results are not stale right away.
+	for _, interval := range []time.Duration{0, -time.Second} {
+		var d = NewCachedHealthChecker(mockHealthChecker, interval, 0)
+		var dUpdated = make(chan struct{}, 2)
+		mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+			dUpdated <- struct{}{}
+			return okReport, nil
+		}).Times(1)
+		d.Start()
+		<-dUpdated
+		waitForUpdate(t, d)
+		time.Sleep(10 * time.Millisecond)
+		d.Stop()
+		assert.Empty(t, dUpdated)
+
+		var results, err = d.Check(context.Background(), "")
+		assert.Nil(t, err)
+		assert.Equal(t, OK, results[0].Status)
+	}
+
+	var (
+		c       = NewCachedHealthChecker(mockHealthChecker, time.Hour, time.Hour)
+		updated = make(chan struct{}, 2)
+	)
+
+	// Stopping a health checker that is not started has no effect.
+	c.Stop()
+
+	// Starting it twice does not start a second background goroutine.
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		updated <- struct{}{}
+		return okReport, nil
+	}).Times(1)
+	c.Start()
+	c.Start()
+	<-updated
+	waitForUpdate(t, c)
+	c.Stop()
+	c.Stop()
+	assert.Empty(t, updated)
+
+	// A stopped health checker can be started again.
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		updated <- struct{}{}
+		return okReport, nil
+	}).Times(1)
+	c.Start()
+	<-updated
+	c.Stop()
+}
+
+func waitForUpdate(t *testing.T, c *CachedHealthChecker) {
+	assert.Eventually(t, func() bool { return !c.LastUpdate().IsZero() }, time.Second, time.Millisecond)
+}
@@ -2,76 +2,223 @@ package common
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
-	"time"
 
 	"github.com/pkg/errors"
 )
 
-// NewCockroachModule returns the cockroach health module.
-func NewCockroachModule(cockroach CockroachClient, enabled bool) *CockroachModule {
-	return &CockroachModule{
+// CockroachOption is an option of the cockroach module.
+type CockroachOption func(*CockroachModule)
+
+// WithCockroachNodesCheck enables the "nodes" check, that reports the live and dead nodes of the cluster. The
+// check is Degraded when a node is dead, and KO when less than a majority of the nodes is live. The
+// decommissioning and decommissioned nodes are ignored.
+func WithCockroachNodesCheck() CockroachOption {
+	return func(m *CockroachModule) {
+		m.nodesCheck = true
+	}
+}
+
+// WithCockroachRangesCheck enables the "ranges" check, that reports the under-replicated and unavailable
+// ranges of the cluster. The check is Degraded when a range is under-replicated, and KO when a range is
+// unavailable.
+func WithCockroachRangesCheck() CockroachOption {
+	return func(m *CockroachModule) {
+		m.rangesCheck = true
+	}
+}
+
+// WithCockroachVersionCheck enables the "version" check, that reports the cluster version. The check is
+// Degraded when the version is not the expected one, if not empty.
+func WithCockroachVersionCheck(expected string) CockroachOption {
+	return func(m *CockroachModule) {
+		m.versionCheck = true
+		m.expectedVersion = expected
+	}
+}
+
+// NewCockroachModule returns the cockroach health module. The "ping" check is always executed, the other
+// checks when they are enabled by the options.
+func NewCockroachModule(cockroach CockroachClient, enabled bool, options ...CockroachOption) *CockroachModule {
+	var m = &CockroachModule{
 		cockroach: cockroach,
 		enabled:   enabled,
 	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
 }
 
 // CockroachModule is the health check module for cockroach.
 type CockroachModule struct {
-	cockroach CockroachClient
-	enabled   bool
+	cockroach       CockroachClient
+	enabled         bool
+	nodesCheck      bool
+	rangesCheck     bool
+	versionCheck    bool
+	expectedVersion string
 }
 
-// CockroachClient is the interface of the cockroach client.
+// CockroachClient is the interface of the cockroach client, e.g. *sql.DB.
 type CockroachClient interface {
 	Ping() error
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// CockroachContextClient is the interface of a cockroach client whose ping honours the context, e.g. *sql.DB.
+// When the CockroachClient implements it, PingContext is used instead of Ping.
+type CockroachContextClient interface {
+	PingContext(context.Context) error
 }
 
-type cockroachReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// HealthCheckNames returns the names of the cockroach health checks.
+func (m *CockroachModule) HealthCheckNames() []string {
+	var names = []string{"ping"}
+	if m.nodesCheck {
+		names = append(names, "nodes")
+	}
+	if m.rangesCheck {
+		names = append(names, "ranges")
+	}
+	if m.versionCheck {
+		names = append(names, "version")
+	}
+	return names
 }
 
 // HealthCheck executes the desired cockroach health check.
-func (m *CockroachModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+func (m *CockroachModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired cockroach health check and returns its results.
+func (m *CockroachModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]cockroachReport{{Name: "cockroach", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("cockroach"), nil
+	}
+
+	var checks = map[string]func(context.Context) CheckResult{
+		"ping":    m.cockroachPing,
+		"nodes":   m.cockroachNodes,
+		"ranges":  m.cockroachRanges,
+		"version": m.cockroachVersion,
 	}
 
-	var reports []cockroachReport
-	switch name {
-	case "":
-		reports = append(reports, m.cockroachPing())
-	case "ping":
-		reports = append(reports, m.cockroachPing())
-	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown cockroach health check name: %v", name))
+	return runNamedChecks(ctx, name, m.HealthCheckNames(), checks)
+}
+
+func (m *CockroachModule) cockroachPing(ctx context.Context) CheckResult {
+	return runCheck(ctx, "ping", func(ctx context.Context) error {
+		if err := m.ping(ctx); err != nil {
+			return errors.Wrap(err, "could not ping cockroach")
+		}
+		return nil
+	})
+}
+
+func (m *CockroachModule) ping(ctx context.Context) error {
+	if c, ok := m.cockroach.(CockroachContextClient); ok {
+		return c.PingContext(ctx)
 	}
+	return m.cockroach.Ping()
+}
 
-	return json.MarshalIndent(reports, "", "  ")
+func (m *CockroachModule) cockroachNodes(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "nodes", func(ctx context.Context) (map[string]interface{}, error) {
+		// The decommissioned nodes are not live either, only the active members of the cluster are counted.
+		var rows, err = m.cockroach.QueryContext(ctx, `SELECT n.node_id, n.is_live FROM crdb_internal.gossip_nodes AS n JOIN crdb_internal.gossip_liveness AS l ON l.node_id = n.node_id WHERE l.membership = 'active'`)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not query cockroach nodes")
+		}
+		defer rows.Close()
+
+		var live int
+		var dead = []int64{}
+		for rows.Next() {
+			var id int64
+			var isLive bool
+			if err = rows.Scan(&id, &isLive); err != nil {
+				return nil, errors.Wrap(err, "could not read cockroach nodes")
+			}
+			if isLive {
+				live++
+			} else {
+				dead = append(dead, id)
+			}
+		}
+		if err = rows.Err(); err != nil {
+			return nil, errors.Wrap(err, "could not read cockroach nodes")
+		}
+
+		var details = map[string]interface{}{
+			"live":       live,
+			"dead":       len(dead),
+			"dead_nodes": dead,
+		}
+
+		switch {
+		case live <= len(dead):
+			return details, errors.Errorf("%d of %d nodes are live, less than a majority", live, live+len(dead))
+		case len(dead) > 0:
+			return details, degraded(errors.Errorf("%d of %d nodes are dead: %v", len(dead), live+len(dead), dead))
+		}
+		return details, nil
+	})
+}
+
+func (m *CockroachModule) cockroachRanges(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "ranges", func(ctx context.Context) (map[string]interface{}, error) {
+		var unavailable, underReplicated int64
+		var err = m.queryRow(ctx, `SELECT COALESCE(sum((metrics->>'ranges.unavailable')::INT), 0), COALESCE(sum((metrics->>'ranges.underreplicated')::INT), 0) FROM crdb_internal.kv_store_status`, &unavailable, &underReplicated)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not query cockroach ranges")
+		}
+
+		var details = map[string]interface{}{
+			"unavailable":      unavailable,
+			"under_replicated": underReplicated,
+		}
+
+		switch {
+		case unavailable > 0:
+			return details, errors.Errorf("%d ranges are unavailable", unavailable)
+		case underReplicated > 0:
+			return details, degraded(errors.Errorf("%d ranges are under-replicated", underReplicated))
+		}
+		return details, nil
+	})
 }
 
-func (m *CockroachModule) cockroachPing() cockroachReport {
-	var name = "ping"
-	var status = OK
+func (m *CockroachModule) cockroachVersion(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "version", func(ctx context.Context) (map[string]interface{}, error) {
+		var version string
+		if err := m.queryRow(ctx, "SHOW CLUSTER SETTING version", &version); err != nil {
+			return nil, errors.Wrap(err, "could not query cockroach cluster version")
+		}
 
-	var now = time.Now()
-	var err = m.cockroach.Ping()
-	var duration = time.Since(now)
+		var details = map[string]interface{}{"version": version}
+		if m.expectedVersion != "" && version != m.expectedVersion {
+			return details, degraded(errors.Errorf("cluster version should be '%s' but is '%s'", m.expectedVersion, version))
+		}
+		return details, nil
+	})
+}
 
+// queryRow executes the query and scans its first row into dest.
+func (m *CockroachModule) queryRow(ctx context.Context, query string, dest ...interface{}) error {
+	var rows, err = m.cockroach.QueryContext(ctx, query)
 	if err != nil {
-		status = KO
-		err = errors.Wrap(err, "could not ping cockroach")
+		return err
 	}
+	defer rows.Close()
 
-	return cockroachReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
 	}
+	return rows.Scan(dest...)
 }
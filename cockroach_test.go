@@ -4,9 +4,12 @@ package common_test
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -138,8 +141,149 @@ func TestCockroachUnkownHealthCheck(t *testing.T) {
 		m               = NewCockroachModule(mockCockroach, enabled)
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+}
+
+func TestCockroachCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewCockroachClient(mockCtrl)
+
+	var (
+		enabled = true
+		m       = NewCockroachModule(mockCockroach, enabled)
+	)
+
+	mockCockroach.EXPECT().Ping().Return(fmt.Errorf("fail")).Times(1)
+	var results, err = m.Check(context.Background(), "ping")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	var r = results[0]
+	assert.Equal(t, "ping", r.Name)
+	assert.Equal(t, KO, r.Status)
+	assert.NotZero(t, r.Duration)
+	assert.Equal(t, "could not ping cockroach: fail", r.Error)
+	assert.False(t, r.Start.After(r.End))
+}
+
+type cockroachContextClient struct{}
+
+func (c *cockroachContextClient) Ping() error {
+	return fmt.Errorf("Ping must not be called")
+}
+
+func (c *cockroachContextClient) PingContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *cockroachContextClient) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, fmt.Errorf("QueryContext must not be called")
+}
+
+func TestCockroachTimeout(t *testing.T) {
+	var (
+		enabled     = true
+		m           = NewCockroachModule(&cockroachContextClient{}, enabled)
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	)
+	defer cancel()
+
+	var results, err = m.Check(ctx, "ping")
+	assert.Nil(t, err)
+
+	var r = results[0]
+	assert.Equal(t, KO, r.Status)
+	assert.Equal(t, ErrTimeout.Error(), r.Error)
+}
+
+// cockroachCluster answers the cockroach cluster queries of the fake database.
+type cockroachCluster struct {
+	nodes           map[int64]bool
+	decommissioned  map[int64]bool
+	unavailable     int64
+	underReplicated int64
+	version         string
+}
+
+func (c *cockroachCluster) query(query string, _ []driver.Value) ([]string, [][]driver.Value, error) {
+	switch {
+	case strings.Contains(query, "crdb_internal.gossip_nodes") && strings.Contains(query, "l.membership = 'active'"):
+		var rows [][]driver.Value
+		for id, live := range c.nodes {
+			if !c.decommissioned[id] {
+				rows = append(rows, []driver.Value{id, live})
+			}
+		}
+		return []string{"node_id", "is_live"}, rows, nil
+	case strings.Contains(query, "crdb_internal.kv_store_status"):
+		return []string{"unavailable", "under_replicated"}, [][]driver.Value{{c.unavailable, c.underReplicated}}, nil
+	case query == "SHOW CLUSTER SETTING version":
+		return []string{"version"}, [][]driver.Value{{c.version}}, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func TestCockroachClusterChecks(t *testing.T) {
+	var cluster = &cockroachCluster{nodes: map[int64]bool{1: true, 2: true, 3: true, 4: false}, decommissioned: map[int64]bool{4: true}, version: "23.1"}
+	var db = openFakeDB(t, &fakeDB{query: cluster.query})
+
+	var m = NewCockroachModule(db, true, WithCockroachNodesCheck(), WithCockroachRangesCheck(), WithCockroachVersionCheck("23.1"))
+	assert.Equal(t, []string{"ping", "nodes", "ranges", "version"}, m.HealthCheckNames())
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 4)
+	for i, name := range []string{"ping", "nodes", "ranges", "version"} {
+		assert.Equal(t, name, results[i].Name)
+		assert.Equal(t, OK, results[i].Status, results[i].Error)
+	}
+	// The decommissioned node is not dead.
+	assert.Equal(t, 3, results[1].Details["live"])
+	assert.Equal(t, 0, results[1].Details["dead"])
+	assert.Equal(t, int64(0), results[2].Details["unavailable"])
+	assert.Equal(t, "23.1", results[3].Details["version"])
+
+	// A dead node, under-replicated ranges and an unexpected version.
+	cluster.nodes[3] = false
+	cluster.underReplicated = 12
+	cluster.version = "22.2"
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[1].Status)
+	assert.Equal(t, "1 of 3 nodes are dead: [3]", results[1].Error)
+	assert.Equal(t, []int64{3}, results[1].Details["dead_nodes"])
+	assert.Equal(t, Degraded, results[2].Status)
+	assert.Equal(t, "12 ranges are under-replicated", results[2].Error)
+	assert.Equal(t, Degraded, results[3].Status)
+	assert.Equal(t, "cluster version should be '23.1' but is '22.2'", results[3].Error)
+
+	// No majority and unavailable ranges.
+	cluster.nodes[2] = false
+	cluster.unavailable = 3
+	results, err = m.Check(context.Background(), "nodes")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "1 of 3 nodes are live, less than a majority", results[0].Error)
+	results, err = m.Check(context.Background(), "ranges")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "3 ranges are unavailable", results[0].Error)
+
+	// Query failures.
+	m = NewCockroachModule(openFakeDB(t, &fakeDB{}), true, WithCockroachNodesCheck(), WithCockroachRangesCheck(), WithCockroachVersionCheck(""))
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	for _, r := range results[1:] {
+		assert.Equal(t, KO, r.Status)
+		assert.Contains(t, r.Error, "could not query cockroach")
 	}
-	assert.Panics(t, f)
+
+	// The checks that are not enabled are unknown.
+	m = NewCockroachModule(db, true)
+	_, err = m.Check(context.Background(), "nodes")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
 }
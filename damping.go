@@ -0,0 +1,175 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DampingPolicy is the flap damping policy of a check.
+type DampingPolicy struct {
+	// FailureThreshold is the number of consecutive failures before a check is reported KO.
+	FailureThreshold int
+	// SuccessThreshold is the number of consecutive successes before a KO check is reported OK again.
+	SuccessThreshold int
+	// FlapThreshold is the number of status changes within FlapWindow from which a check is flapping.
+	// Flapping checks are reported Degraded. Zero disables the flap detection.
+	FlapThreshold int
+	FlapWindow    time.Duration
+}
+
+// MakeFlapDampingMW makes a middleware that damps the status changes of the checks according to their
+// policy. The policies are keyed by check name, e.g. "ping", or by module and check name for the results of
+// a Registry, e.g. "influx/ping". The latter takes precedence, and the policy with key "" applies to the
+// other checks. The checks without policy are not damped.
+// The damped results keep their error, and their details contain the consecutive failures and successes,
+// and whether they are flapping. Deactivated checks are not damped, and their state is reset.
+func MakeFlapDampingMW(policies map[string]DampingPolicy) func(HealthChecker) HealthChecker {
+	return func(next HealthChecker) HealthChecker {
+		return &flapDampingMW{
+			policies: policies,
+			states:   map[string]*dampingState{},
+			next:     next,
+		}
+	}
+}
+
+type flapDampingMW struct {
+	policies map[string]DampingPolicy
+	next     HealthChecker
+
+	mutex  sync.Mutex
+	states map[string]*dampingState
+}
+
+type dampingState struct {
+	reported  Status
+	failures  int
+	successes int
+	failing   bool
+	changes   []time.Time
+}
+
+// HealthCheck implements HealthChecker.
+func (m *flapDampingMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var report, err = m.next.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CheckResult
+	results, err = decodeReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return encodeReport(report, m.apply(results))
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (m *flapDampingMW) HealthCheckNames() []string {
+	return healthCheckNames(m.next)
+}
+
+// Check implements Checker.
+func (m *flapDampingMW) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var results, err = check(ctx, m.next, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.apply(results), nil
+}
+
+func (m *flapDampingMW) policy(r CheckResult) (DampingPolicy, bool) {
+	for _, key := range []string{r.Module + "/" + r.Name, r.Name, ""} {
+		if p, ok := m.policies[key]; ok {
+			return p, true
+		}
+	}
+	return DampingPolicy{}, false
+}
+
+func (m *flapDampingMW) apply(results []CheckResult) []CheckResult {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, r := range results {
+		var p, ok = m.policy(r)
+		if !ok {
+			continue
+		}
+
+		var key = r.Module + "/" + r.Name
+		if r.Status == Deactivated {
+			delete(m.states, key)
+			continue
+		}
+
+		var s, exists = m.states[key]
+		if !exists {
+			s = &dampingState{reported: OK}
+			m.states[key] = s
+		}
+		results[i] = s.damp(p, r)
+	}
+	return results
+}
+
+// damp updates the state with the result, and returns the damped result.
+func (s *dampingState) damp(p DampingPolicy, r CheckResult) CheckResult {
+	var now = r.End
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var failing = r.Status == KO
+	if failing {
+		s.failures++
+		s.successes = 0
+	} else {
+		s.successes++
+		s.failures = 0
+	}
+
+	if failing != s.failing {
+		s.changes = append(s.changes, now)
+	}
+	s.failing = failing
+
+	// Forget the status changes out of the window.
+	var i = 0
+	for i < len(s.changes) && now.Sub(s.changes[i]) > p.FlapWindow {
+		i++
+	}
+	s.changes = s.changes[i:]
+	var flapping = p.FlapThreshold > 0 && len(s.changes) >= p.FlapThreshold
+
+	switch {
+	case failing && s.failures >= p.FailureThreshold:
+		s.reported = KO
+	case !failing && s.reported == KO && s.successes >= p.SuccessThreshold:
+		s.reported = OK
+	}
+
+	switch {
+	case s.reported == KO && !failing:
+		r.Status = KO
+		r.Error = fmt.Sprintf("recovering, %d of %d consecutive successes", s.successes, p.SuccessThreshold)
+	case s.reported == OK && failing:
+		r.Status = OK
+	}
+
+	if flapping && r.Status != KO {
+		r.Status = Degraded
+		r.Error = fmt.Sprintf("flapping, %d status changes in %v", len(s.changes), p.FlapWindow)
+	}
+
+	if r.Details == nil {
+		r.Details = map[string]interface{}{}
+	}
+	r.Details["consecutive_failures"] = s.failures
+	r.Details["consecutive_successes"] = s.successes
+	r.Details["flapping"] = flapping
+	return r
+}
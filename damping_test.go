@@ -0,0 +1,107 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFlapDampingMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var m = MakeFlapDampingMW(map[string]DampingPolicy{
+		"ping": {FailureThreshold: 3, SuccessThreshold: 2},
+	})(mockHealthChecker)
+
+	var steps = []struct {
+		report   json.RawMessage
+		expected Status
+		err      string
+	}{
+		{koReport, OK, "fail"},
+		{koReport, OK, "fail"},
+		{okReport, OK, ""},
+		{koReport, OK, "fail"},
+		{koReport, OK, "fail"},
+		{koReport, KO, "fail"},
+		{koReport, KO, "fail"},
+		{okReport, KO, "recovering, 1 of 2 consecutive successes"},
+		{koReport, KO, "fail"},
+		{okReport, KO, "recovering, 1 of 2 consecutive successes"},
+		{okReport, OK, ""},
+		{okReport, OK, ""},
+	}
+
+	for i, s := range steps {
+		mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(s.report, nil).Times(1)
+		var report, err = m.HealthCheck(context.Background(), "")
+		assert.Nil(t, err)
+
+		var results []CheckResult
+		assert.Nil(t, json.Unmarshal(report, &results))
+		assert.Equal(t, s.expected, results[0].Status, "step %d", i)
+		assert.Equal(t, s.err, results[0].Error, "step %d", i)
+		assert.Equal(t, false, results[0].Details["flapping"], "step %d", i)
+	}
+}
+
+func TestFlapDampingMWFlapping(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var m = MakeFlapDampingMW(map[string]DampingPolicy{
+		"":           {FailureThreshold: 1, SuccessThreshold: 1},
+		"redis/ping": {FailureThreshold: 2, SuccessThreshold: 1, FlapThreshold: 4, FlapWindow: time.Hour},
+	})(r).(Checker)
+
+	var steps = []struct {
+		err      error
+		expected Status
+		flapping bool
+	}{
+		{assert.AnError, OK, false},
+		{nil, OK, false},
+		{assert.AnError, OK, false},
+		{nil, Degraded, true},
+		{assert.AnError, Degraded, true},
+		{assert.AnError, KO, true},
+	}
+
+	for i, s := range steps {
+		mockRedis.EXPECT().Do("PING").Return(nil, s.err).Times(1)
+		var results, err = m.Check(context.Background(), "redis")
+		assert.Nil(t, err)
+		assert.Equal(t, s.expected, results[0].Status, "step %d", i)
+		assert.Equal(t, s.flapping, results[0].Details["flapping"], "step %d", i)
+	}
+}
+
+func TestFlapDampingMWNoPolicy(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var m = MakeFlapDampingMW(map[string]DampingPolicy{
+		"write": {FailureThreshold: 3},
+	})(mockHealthChecker)
+
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(koReport, nil).Times(1)
+	var report, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var results []CheckResult
+	assert.Nil(t, json.Unmarshal(report, &results))
+	assert.Equal(t, KO, results[0].Status)
+	assert.Nil(t, results[0].Details)
+}
@@ -3,8 +3,6 @@ package common
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"time"
 
 	"github.com/pkg/errors"
 )
@@ -28,50 +26,40 @@ type FlakiClient interface {
 	NextID(context.Context) (string, error)
 }
 
-type flakiReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// HealthCheckNames returns the names of the flaki health checks.
+func (m *FlakiModule) HealthCheckNames() []string {
+	return []string{"ping"}
 }
 
-// HealthCheck executes the desired influx health check.
-func (m *FlakiModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+// HealthCheck executes the desired flaki health check.
+func (m *FlakiModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired flaki health check and returns its results.
+func (m *FlakiModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]influxReport{{Name: "flaki", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("flaki"), nil
 	}
 
-	var reports []flakiReport
+	var results []CheckResult
 	switch name {
 	case "":
-		reports = append(reports, m.nextID())
+		results = runChecks(ctx, m.nextID)
 	case "ping":
-		reports = append(reports, m.nextID())
+		results = append(results, m.nextID(ctx))
 	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown influx health check name: %v", name))
+		return nil, &ErrInvalidHCName{name}
 	}
 
-	return json.MarshalIndent(reports, "", "  ")
+	return results, nil
 }
 
-func (m *FlakiModule) nextID() flakiReport {
-	var name = "nextid"
-	var status = OK
-
-	var now = time.Now()
-	var _, err = m.flakiClient.NextID(context.Background())
-	var duration = time.Since(now)
-
-	if err != nil {
-		status = KO
-		err = errors.Wrap(err, "could not get ID from flaki")
-	}
-
-	return flakiReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
-	}
+func (m *FlakiModule) nextID(ctx context.Context) CheckResult {
+	return runCheck(ctx, "nextid", func(ctx context.Context) error {
+		if _, err := m.flakiClient.NextID(ctx); err != nil {
+			return errors.Wrap(err, "could not get ID from flaki")
+		}
+		return nil
+	})
 }
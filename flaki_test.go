@@ -140,8 +140,30 @@ func TestFlakiUnkownHealthCheck(t *testing.T) {
 		m               = NewFlakiModule(mockFlakiClient, enabled)
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
-	}
-	assert.Panics(t, f)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+}
+
+func TestFlakiContext(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockFlakiClient = mock.NewFlakiClient(mockCtrl)
+
+	var (
+		enabled     = true
+		m           = NewFlakiModule(mockFlakiClient, enabled)
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	)
+	defer cancel()
+
+	// The context of the health check is given to flaki.
+	mockFlakiClient.EXPECT().NextID(ctx).DoAndReturn(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}).Times(1)
+	var results, err = m.Check(ctx, "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, ErrTimeout.Error(), results[0].Error)
 }
@@ -3,7 +3,10 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+
+	"github.com/pkg/errors"
 )
 
 // Status is the status of the health check.
@@ -16,18 +19,62 @@ const (
 	KO
 	// Deactivated is the status for a service that is deactivated, e.g. we can disable error tracking, instrumenting, tracing,...
 	Deactivated
+	// Degraded is the status for a successful health check that should be looked at, e.g. it was too slow.
+	Degraded
 )
 
+// MarshalText implements encoding.TextMarshaler, so that the status is encoded as its name in the reports.
+func (i Status) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *Status) UnmarshalText(text []byte) error {
+	for s := Status(0); int(s) < len(_Status_index)-1; s++ {
+		if s.String() == string(text) {
+			*i = s
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown health check status '%s'", string(text))
+}
+
 // HealthChecker is the interface of the health check modules.
 type HealthChecker interface {
 	HealthCheck(context.Context, string) (json.RawMessage, error)
 }
 
+// Checker is the typed counterpart of HealthChecker: it returns the results of the health checks instead
+// of their JSON report.
+type Checker interface {
+	Check(context.Context, string) ([]CheckResult, error)
+}
+
+// HealthCheckNamer is the interface of the health check modules that expose the names of their health
+// checks, i.e. the valid names for HealthCheck besides "".
+type HealthCheckNamer interface {
+	HealthCheckNames() []string
+}
+
 // HTTPClient is the interface of the http client used to get health check status.
 type HTTPClient interface {
 	Get(string) (*http.Response, error)
 }
 
+// HTTPRequestClient is the interface of a http client that executes requests, e.g. *http.Client. When the
+// HTTPClient implements it, the requests carry the context of the health check.
+type HTTPRequestClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+var (
+	// ErrTimeout is the error reported by a health check that did not complete before the deadline of
+	// its context.
+	ErrTimeout = errors.New("timeout: health check did not complete before the deadline")
+	// ErrCanceled is the error reported by a health check whose context was canceled.
+	ErrCanceled = errors.New("health check canceled")
+)
+
 // str return the string error that will be in the health report
 func str(err error) string {
 	if err == nil {
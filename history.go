@@ -0,0 +1,325 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewHistory returns a health checker that records the last size results of each check of next. A size
+// lower than 1 is replaced by defaultHistorySize.
+func NewHistory(next HealthChecker, size int) *History {
+	if size < 1 {
+		size = defaultHistorySize
+	}
+
+	return &History{
+		next:    next,
+		size:    size,
+		buffers: map[string]*ring{},
+	}
+}
+
+// defaultHistorySize is the number of results recorded for each check when the given size is not valid.
+const defaultHistorySize = 100
+
+// History is a HealthChecker that keeps a bounded history of the results of each check. The checks are
+// identified by their name, prefixed by their module for the results of a Registry, e.g. "redis/ping".
+type History struct {
+	next HealthChecker
+	size int
+
+	mutex   sync.RWMutex
+	buffers map[string]*ring
+}
+
+// HistoryStats are the statistics of a check over a window. The uptime is the percentage of results that
+// are not KO, and the latencies are computed on the results that are not Deactivated.
+type HistoryStats struct {
+	Count          int
+	Uptime         float64
+	AverageLatency time.Duration
+	P50Latency     time.Duration
+	P95Latency     time.Duration
+	P99Latency     time.Duration
+}
+
+// MarshalJSON implements json.Marshaler. The latencies are marshalled as strings, like the durations of
+// the results.
+func (s HistoryStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count          int     `json:"count"`
+		Uptime         float64 `json:"uptime"`
+		AverageLatency string  `json:"average_latency"`
+		P50Latency     string  `json:"p50_latency"`
+		P95Latency     string  `json:"p95_latency"`
+		P99Latency     string  `json:"p99_latency"`
+	}{
+		Count:          s.Count,
+		Uptime:         s.Uptime,
+		AverageLatency: s.AverageLatency.String(),
+		P50Latency:     s.P50Latency.String(),
+		P95Latency:     s.P95Latency.String(),
+		P99Latency:     s.P99Latency.String(),
+	})
+}
+
+// HealthCheck implements HealthChecker.
+func (h *History) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var report, err = h.next.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if results, err := decodeReport(report); err == nil {
+		h.record(results)
+	}
+	return report, nil
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (h *History) HealthCheckNames() []string {
+	return healthCheckNames(h.next)
+}
+
+// Check implements Checker.
+func (h *History) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var results, err = check(ctx, h.next, name)
+	if err != nil {
+		return nil, err
+	}
+
+	h.record(results)
+	return results, nil
+}
+
+func (h *History) record(results []CheckResult) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var now = time.Now()
+	for _, r := range results {
+		if r.End.IsZero() {
+			r.End = now
+		}
+
+		var name = historyName(r)
+		var b, ok = h.buffers[name]
+		if !ok {
+			b = &ring{results: make([]CheckResult, 0, h.size)}
+			h.buffers[name] = b
+		}
+		b.add(r)
+	}
+}
+
+// Checks returns the sorted names of the recorded checks.
+func (h *History) Checks() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	var names = make([]string, 0, len(h.buffers))
+	for name := range h.buffers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Results returns the recorded results of the check that ended in [from, to], oldest first. A zero time is
+// not a bound. The second return value is false when the check is unknown.
+func (h *History) Results(name string, from, to time.Time) ([]CheckResult, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	var b, ok = h.buffers[name]
+	if !ok {
+		return nil, false
+	}
+
+	var results = []CheckResult{}
+	for _, r := range b.all() {
+		if (from.IsZero() || !r.End.Before(from)) && (to.IsZero() || !r.End.After(to)) {
+			results = append(results, r)
+		}
+	}
+	return results, true
+}
+
+// Stats returns the statistics of the check over [from, to], see Results. The second return value is
+// false when the check is unknown.
+func (h *History) Stats(name string, from, to time.Time) (HistoryStats, bool) {
+	var results, ok = h.Results(name, from, to)
+	if !ok {
+		return HistoryStats{}, false
+	}
+	return computeStats(results), true
+}
+
+func computeStats(results []CheckResult) HistoryStats {
+	var stats HistoryStats
+	var latencies []time.Duration
+	var up int
+	var total time.Duration
+
+	for _, r := range results {
+		if r.Status == Deactivated {
+			continue
+		}
+		if r.Status != KO {
+			up++
+		}
+		latencies = append(latencies, r.Duration)
+		total += r.Duration
+	}
+
+	stats.Count = len(latencies)
+	if stats.Count == 0 {
+		return stats
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.Uptime = 100 * float64(up) / float64(stats.Count)
+	stats.AverageLatency = total / time.Duration(stats.Count)
+	stats.P50Latency = percentile(latencies, 50)
+	stats.P95Latency = percentile(latencies, 95)
+	stats.P99Latency = percentile(latencies, 99)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	var rank = int(math.Ceil(p / 100 * float64(len(latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+func historyName(r CheckResult) string {
+	if r.Module == "" {
+		return r.Name
+	}
+	return r.Module + "/" + r.Name
+}
+
+// ring is a fixed size circular buffer of results.
+type ring struct {
+	results []CheckResult
+	next    int
+}
+
+func (b *ring) add(r CheckResult) {
+	if len(b.results) < cap(b.results) {
+		b.results = append(b.results, r)
+		return
+	}
+	if len(b.results) == 0 {
+		return
+	}
+	b.results[b.next] = r
+	b.next = (b.next + 1) % len(b.results)
+}
+
+// all returns the results, oldest first.
+func (b *ring) all() []CheckResult {
+	var results = make([]CheckResult, 0, len(b.results))
+	results = append(results, b.results[b.next:]...)
+	return append(results, b.results[:b.next]...)
+}
+
+type historyResponse struct {
+	Name    string        `json:"name"`
+	Stats   HistoryStats  `json:"stats"`
+	Results []CheckResult `json:"results"`
+}
+
+// MakeHistoryHandler makes a HTTP handler that serves the names of the recorded checks under /history,
+// and the history of the checks under /history/{check} and /history/{module}/{check}, along with its
+// statistics. The optional from and to query parameters, RFC3339 times, bound the history, e.g.
+// "?from=2024-06-10T03:00:00Z&to=2024-06-10T03:30:00Z". The optional window query parameter, e.g.
+// "?window=1h", restricts the history to the window before to, or now, and cannot be combined with from.
+// The handler replies 404 when the check is unknown, and 400 when the parameters are invalid.
+func MakeHistoryHandler(h *History) http.Handler {
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = r.Context()
+
+		var name, _ = decodeHealthCheckRequest(ctx, r)
+
+		var from, to, err = historyRange(r.URL.Query())
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		var results, ok = h.Results(name.(string), from, to)
+		if !ok {
+			encodeHealthCheckError(ctx, &ErrInvalidHCName{name.(string)}, w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(historyResponse{
+			Name:    name.(string),
+			Stats:   computeStats(results),
+			Results: results,
+		})
+	})
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("GET /history", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(h.Checks())
+	})
+	mux.Handle("GET /history/{module}", handler)
+	mux.Handle("GET /history/{module}/{check}", handler)
+	return mux
+}
+
+// historyRange returns the bounds of the history from the from, to and window query parameters. A zero time
+// is not a bound.
+func historyRange(query url.Values) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.Errorf("invalid from %s", v)
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, errors.Errorf("invalid to %s", v)
+		}
+	}
+
+	if window := query.Get("window"); window != "" {
+		var d time.Duration
+		if d, err = time.ParseDuration(window); err != nil {
+			return from, to, errors.Errorf("invalid window %s", window)
+		}
+		if !from.IsZero() {
+			return from, to, errors.New("window and from cannot be combined")
+		}
+
+		var end = to
+		if end.IsZero() {
+			end = time.Now()
+		}
+		from = end.Add(-d)
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, errors.New("from is after to")
+	}
+	return from, to, nil
+}
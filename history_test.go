@@ -0,0 +1,198 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHistory(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var h = NewHistory(mockHealthChecker, 4)
+	var report = func(status string, duration string) json.RawMessage {
+		return json.RawMessage(`{"status": "` + status + `", "modules": {"redis": [{"name": "ping", "status": "` + status + `", "duration": "` + duration + `"}], "influx": [{"name": "influx", "status": "Deactivated"}]}}`)
+	}
+
+	// Unknown check.
+	var _, ok = h.Results("redis/ping", time.Time{}, time.Time{})
+	assert.False(t, ok)
+
+	for _, r := range []json.RawMessage{report("KO", "1s"), report("OK", "10ms"), report("KO", "40ms"), report("OK", "20ms"), report("OK", "30ms")} {
+		mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(r, nil).Times(1)
+		var _, err = h.HealthCheck(context.Background(), "")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"influx/influx", "redis/ping"}, h.Checks())
+
+	// Only the last 4 results are kept, oldest first.
+	var results []CheckResult
+	results, ok = h.Results("redis/ping", time.Time{}, time.Time{})
+	assert.True(t, ok)
+	assert.Len(t, results, 4)
+	assert.Equal(t, 10*time.Millisecond, results[0].Duration)
+	assert.Equal(t, 30*time.Millisecond, results[3].Duration)
+	assert.False(t, results[0].End.IsZero())
+
+	var stats HistoryStats
+	stats, ok = h.Stats("redis/ping", time.Time{}, time.Time{})
+	assert.True(t, ok)
+	assert.Equal(t, 4, stats.Count)
+	assert.Equal(t, 75.0, stats.Uptime)
+	assert.Equal(t, 25*time.Millisecond, stats.AverageLatency)
+	assert.Equal(t, 20*time.Millisecond, stats.P50Latency)
+	assert.Equal(t, 40*time.Millisecond, stats.P95Latency)
+	assert.Equal(t, 40*time.Millisecond, stats.P99Latency)
+
+	// Deactivated checks are not in the statistics.
+	stats, ok = h.Stats("influx/influx", time.Time{}, time.Time{})
+	assert.True(t, ok)
+	assert.Equal(t, HistoryStats{}, stats)
+
+	// Window.
+	results, _ = h.Results("redis/ping", time.Now().Add(time.Hour), time.Time{})
+	assert.Empty(t, results)
+	results, _ = h.Results("redis/ping", time.Time{}, time.Now().Add(-time.Hour))
+	assert.Empty(t, results)
+}
+
+func TestHistoryInvalidSize(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var report = json.RawMessage(`{"status": "OK", "modules": {"redis": [{"name": "ping", "status": "OK"}]}}`)
+
+	// The results are recorded with the default size.
+	for _, size := range []int{0, -1} {
+		var h = NewHistory(mockHealthChecker, size)
+		mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(report, nil).Times(2)
+		for i := 0; i < 2; i++ {
+			var _, err = h.HealthCheck(context.Background(), "")
+			assert.Nil(t, err)
+		}
+
+		var results, ok = h.Results("redis/ping", time.Time{}, time.Time{})
+		assert.True(t, ok)
+		assert.Len(t, results, 2)
+	}
+}
+
+func TestHistoryCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var h = NewHistory(r, 10)
+
+	mockRedis.EXPECT().Do("PING").Return(nil, assert.AnError).Times(1)
+	var results, err = h.Check(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+
+	var history, ok = h.Results("redis/ping", time.Time{}, time.Time{})
+	assert.True(t, ok)
+	assert.Equal(t, results, history)
+}
+
+func TestHistoryHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var h = NewHistory(mockHealthChecker, 10)
+	var s = httptest.NewServer(MakeHistoryHandler(h))
+	defer s.Close()
+
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(koReport, nil).Times(1)
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1)
+	h.HealthCheck(context.Background(), "")
+	h.HealthCheck(context.Background(), "")
+
+	// Checks.
+	var res, err = http.Get(s.URL + "/history")
+	assert.Nil(t, err)
+	var names []string
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&names))
+	assert.Equal(t, []string{"ping"}, names)
+
+	// History.
+	res, err = http.Get(s.URL + "/history/ping?window=1h")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+
+	var body struct {
+		Name  string `json:"name"`
+		Stats struct {
+			Count          int     `json:"count"`
+			Uptime         float64 `json:"uptime"`
+			AverageLatency string  `json:"average_latency"`
+		} `json:"stats"`
+		Results []CheckResult `json:"results"`
+	}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+	assert.Equal(t, "ping", body.Name)
+	assert.Equal(t, 2, body.Stats.Count)
+	assert.Equal(t, 50.0, body.Stats.Uptime)
+	assert.Equal(t, "1ms", body.Stats.AverageLatency)
+	assert.Len(t, body.Results, 2)
+	assert.Equal(t, KO, body.Results[0].Status)
+
+	// Unknown check.
+	res, err = http.Get(s.URL + "/history/redis/write")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// Absolute range.
+	var (
+		first, _ = h.Results("ping", time.Time{}, time.Time{})
+		end      = first[0].End.Truncate(time.Second)
+		rng      = func(from, to time.Time) string {
+			return url.Values{"from": {from.Format(time.RFC3339)}, "to": {to.Format(time.RFC3339)}}.Encode()
+		}
+	)
+	for _, tst := range []struct {
+		query string
+		count int
+	}{
+		{rng(end.Add(-time.Hour), end.Add(time.Hour)), 2},
+		{rng(end.Add(-2*time.Hour), end.Add(-time.Hour)), 0},
+		{url.Values{"from": {end.Add(time.Hour).Format(time.RFC3339)}}.Encode(), 0},
+		{url.Values{"to": {end.Add(-time.Hour).Format(time.RFC3339)}}.Encode(), 0},
+		{url.Values{"to": {end.Add(time.Hour).Format(time.RFC3339)}, "window": {"3h"}}.Encode(), 2},
+		{url.Values{"to": {end.Add(time.Hour).Format(time.RFC3339)}, "window": {"30m"}}.Encode(), 0},
+	} {
+		res, err = http.Get(s.URL + "/history/ping?" + tst.query)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode, tst.query)
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, tst.count, body.Stats.Count, tst.query)
+		assert.Len(t, body.Results, tst.count, tst.query)
+	}
+
+	// Invalid parameters.
+	for _, query := range []string{
+		"window=yesterday",
+		"from=yesterday",
+		"to=2024-06-10",
+		"from=2024-06-10T03:12:00Z&window=1h",
+		"from=2024-06-10T03:12:00Z&to=2024-06-10T03:00:00Z",
+	} {
+		res, err = http.Get(s.URL + "/history/ping?" + query)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+)
+
+// MakeHealthCheckHandler makes a HTTP handler that serves the health checks under /health, /health/{module}
+// and /health/{module}/{check}. The route is translated to the health check name "", "<module>" or
+// "<module>/<check>", as expected by the Registry.
+// The handler replies 200 when all checks are OK, Degraded or Deactivated, 503 when a check is KO and 404 when the
+// health check name is unknown.
+func MakeHealthCheckHandler(hc HealthChecker) http.Handler {
+	return routeHealthChecks(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = r.Context()
+
+		var name, _ = decodeHealthCheckRequest(ctx, r)
+		var report, err = hc.HealthCheck(ctx, name.(string))
+		if err != nil {
+			encodeHealthCheckError(ctx, err, w)
+			return
+		}
+		encodeHealthCheckResponse(ctx, w, report)
+	}))
+}
+
+// MakeHealthCheckEndpoint makes the go-kit endpoint of the health checker. The request is the health check
+// name and the response the json.RawMessage report.
+func MakeHealthCheckEndpoint(hc HealthChecker) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return hc.HealthCheck(ctx, req.(string))
+	}
+}
+
+// MakeGoKitHealthCheckHandler makes the go-kit HTTP transport for the health check endpoint. It serves the
+// same routes with the same status codes as MakeHealthCheckHandler.
+func MakeGoKitHealthCheckHandler(e endpoint.Endpoint, options ...httptransport.ServerOption) http.Handler {
+	var opts = append([]httptransport.ServerOption{httptransport.ServerErrorEncoder(encodeHealthCheckError)}, options...)
+	return routeHealthChecks(httptransport.NewServer(e, decodeHealthCheckRequest, encodeHealthCheckResponse, opts...))
+}
+
+func routeHealthChecks(h http.Handler) http.Handler {
+	var mux = http.NewServeMux()
+	mux.Handle("GET /health", h)
+	mux.Handle("GET /health/{module}", h)
+	mux.Handle("GET /health/{module}/{check}", h)
+	return mux
+}
+
+// decodeHealthCheckRequest returns the health check name from the route.
+func decodeHealthCheckRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var module, check = r.PathValue("module"), r.PathValue("check")
+	if check == "" {
+		return module, nil
+	}
+	return module + "/" + check, nil
+}
+
+func encodeHealthCheckResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	var report = response.(json.RawMessage)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if reportStatus(report) == KO {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var _, err = w.Write(report)
+	return err
+}
+
+func encodeHealthCheckError(_ context.Context, err error, w http.ResponseWriter) {
+	var status = http.StatusInternalServerError
+	var invalid *ErrInvalidHCName
+	if errors.As(err, &invalid) {
+		status = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,79 @@
+package common_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthCheckHandlers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		deactivatedReport = json.RawMessage(`[{"name": "redis", "status": "Deactivated"}]`)
+		registryKOReport  = json.RawMessage(`{"status": "KO", "modules": {}}`)
+		degradedReport    = json.RawMessage(`[{"name": "ping", "status": "Degraded", "duration": "4s"}]`)
+	)
+
+	var tsts = []struct {
+		path           string
+		hcName         string
+		report         json.RawMessage
+		err            error
+		expectedStatus int
+	}{
+		{"/health", "", okReport, nil, http.StatusOK},
+		{"/health", "", registryKOReport, nil, http.StatusServiceUnavailable},
+		{"/health/redis", "redis", deactivatedReport, nil, http.StatusOK},
+		{"/health/redis", "redis", koReport, nil, http.StatusServiceUnavailable},
+		{"/health/redis/ping", "redis/ping", okReport, nil, http.StatusOK},
+		{"/health/redis/ping", "redis/ping", degradedReport, nil, http.StatusOK},
+		{"/health/redis/unknown", "redis/unknown", nil, &ErrInvalidHCName{}, http.StatusNotFound},
+		{"/health/redis/unknown", "redis/unknown", nil, fmt.Errorf("wrapped: %w", &ErrInvalidHCName{}), http.StatusNotFound},
+		{"/health/redis/ping", "redis/ping", nil, fmt.Errorf("fail"), http.StatusInternalServerError},
+	}
+
+	var handlers = map[string]http.Handler{
+		"net/http": MakeHealthCheckHandler(mockHealthChecker),
+		"go-kit":   MakeGoKitHealthCheckHandler(MakeHealthCheckEndpoint(mockHealthChecker)),
+	}
+
+	for handlerName, h := range handlers {
+		var s = httptest.NewServer(h)
+
+		for _, tst := range tsts {
+			mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), tst.hcName).Return(tst.report, tst.err).Times(1)
+
+			var res, err = http.Get(s.URL + tst.path)
+			assert.Nil(t, err)
+			assert.Equal(t, tst.expectedStatus, res.StatusCode, "%s %s", handlerName, tst.path)
+			assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+
+			var body, _ = io.ReadAll(res.Body)
+			res.Body.Close()
+			if tst.err == nil {
+				assert.Equal(t, string(tst.report), string(body))
+			} else {
+				assert.Contains(t, string(body), tst.err.Error())
+			}
+		}
+
+		// Route not served.
+		var res, err = http.Get(s.URL + "/other")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		res.Body.Close()
+
+		s.Close()
+	}
+}
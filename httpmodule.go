@@ -0,0 +1,205 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxHTTPBodySize is the maximum size of the response body read by the HTTP checks.
+const maxHTTPBodySize = 1 << 20
+
+// HTTPCheck is the configuration of a HTTP health check.
+type HTTPCheck struct {
+	// Name is the name of the check.
+	Name string
+	// URL is the URL to query.
+	URL string
+	// Method is the HTTP method, GET by default. The methods other than GET, and the headers, require the
+	// HTTPClient to implement HTTPRequestClient.
+	Method  string
+	Headers map[string]string
+	// ExpectedStatus are the expected status codes. By default, any 2xx status code is expected.
+	ExpectedStatus []int
+	// BodyContains is a substring that the response body must contain, if not empty.
+	BodyContains string
+	// BodyRegexp is a regular expression that the response body must match, if not nil.
+	BodyRegexp *regexp.Regexp
+	// JSONPath is the path of a value in the JSON response body, with the keys and array indexes separated
+	// by dots, e.g. "checks.0.status". The value must exist and, when JSONValue is not empty, be equal to it.
+	JSONPath  string
+	JSONValue string
+	// Timeout is the timeout of the check. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// NewHTTPModule returns the HTTP health module, executing the given checks.
+func NewHTTPModule(httpClient HTTPClient, checks []HTTPCheck, enabled bool) *HTTPModule {
+	return &HTTPModule{
+		httpClient: httpClient,
+		checks:     checks,
+		enabled:    enabled,
+	}
+}
+
+// HTTPModule is the health check module for HTTP endpoints.
+type HTTPModule struct {
+	httpClient HTTPClient
+	checks     []HTTPCheck
+	enabled    bool
+}
+
+// HealthCheckNames returns the names of the HTTP health checks.
+func (m *HTTPModule) HealthCheckNames() []string {
+	var names = make([]string, 0, len(m.checks))
+	for _, c := range m.checks {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// HealthCheck executes the desired HTTP health check.
+func (m *HTTPModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired HTTP health check and returns its results.
+func (m *HTTPModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	if !m.enabled {
+		return deactivated("http"), nil
+	}
+
+	if name == "" {
+		var checks []func(context.Context) CheckResult
+		for _, c := range m.checks {
+			checks = append(checks, m.httpCheck(c))
+		}
+		return runChecks(ctx, checks...), nil
+	}
+
+	for _, c := range m.checks {
+		if c.Name == name {
+			return []CheckResult{m.httpCheck(c)(ctx)}, nil
+		}
+	}
+	return nil, &ErrInvalidHCName{name}
+}
+
+func (m *HTTPModule) httpCheck(c HTTPCheck) func(context.Context) CheckResult {
+	return func(ctx context.Context) CheckResult {
+		if c.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+		}
+
+		return runCheck(ctx, c.Name, func(ctx context.Context) error {
+			return c.execute(ctx, m.httpClient)
+		})
+	}
+}
+
+// execute queries the URL and checks the response.
+func (c HTTPCheck) execute(ctx context.Context, client HTTPClient) error {
+	var res, err = c.do(ctx, client)
+	if err != nil {
+		return errors.Wrapf(err, "could not query %s", c.URL)
+	}
+	defer res.Body.Close()
+
+	if !c.expectedStatus(res.StatusCode) {
+		return errors.Errorf("%s returned invalid status code: %v", c.URL, res.StatusCode)
+	}
+
+	if c.BodyContains == "" && c.BodyRegexp == nil && c.JSONPath == "" {
+		return nil
+	}
+
+	var body []byte
+	body, err = io.ReadAll(io.LimitReader(res.Body, maxHTTPBodySize))
+	if err != nil {
+		return errors.Wrap(err, "could not read response body")
+	}
+
+	if c.BodyContains != "" && !strings.Contains(string(body), c.BodyContains) {
+		return errors.Errorf("response should contain '%s' but is: %s", c.BodyContains, body)
+	}
+	if c.BodyRegexp != nil && !c.BodyRegexp.Match(body) {
+		return errors.Errorf("response should match '%s' but is: %s", c.BodyRegexp, body)
+	}
+	if c.JSONPath != "" {
+		return c.checkJSON(body)
+	}
+	return nil
+}
+
+func (c HTTPCheck) do(ctx context.Context, client HTTPClient) (*http.Response, error) {
+	var method = c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var rc, ok = client.(HTTPRequestClient)
+	if !ok {
+		if method != http.MethodGet || len(c.Headers) > 0 {
+			return nil, errors.Errorf("the http client cannot issue %s requests or set headers", method)
+		}
+		return client.Get(c.URL)
+	}
+
+	var req, err = http.NewRequestWithContext(ctx, method, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	return rc.Do(req)
+}
+
+func (c HTTPCheck) expectedStatus(code int) bool {
+	if len(c.ExpectedStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, expected := range c.ExpectedStatus {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func (c HTTPCheck) checkJSON(body []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return errors.Wrap(err, "could not decode json response")
+	}
+
+	for _, key := range strings.Split(c.JSONPath, ".") {
+		var found bool
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value, found = v[key]
+		case []interface{}:
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(v) {
+				value, found = v[i], true
+			}
+		}
+		if !found {
+			return errors.Errorf("json path '%s' not found in response", c.JSONPath)
+		}
+	}
+
+	if c.JSONValue != "" && fmt.Sprint(value) != c.JSONValue {
+		return errors.Errorf("json path '%s' should be '%s' but is: %v", c.JSONPath, c.JSONValue, value)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package common_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHTTPTestServer() *httptest.Server {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "pass", "checks": [{"name": "db", "healthy": true}], "version": "1.2.3"}`))
+	})
+	mux.HandleFunc("HEAD /ready", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPModule(t *testing.T) {
+	var s = newHTTPTestServer()
+	defer s.Close()
+
+	var m = NewHTTPModule(s.Client(), []HTTPCheck{
+		{Name: "health", URL: s.URL + "/health", BodyContains: `"pass"`, BodyRegexp: regexp.MustCompile(`"version": "1\.\d+\.\d+"`), JSONPath: "checks.0.healthy", JSONValue: "true"},
+		{Name: "ready", URL: s.URL + "/ready", Method: http.MethodHead, Headers: map[string]string{"Authorization": "Bearer token"}, ExpectedStatus: []int{http.StatusNoContent}},
+		{Name: "missing", URL: s.URL + "/missing", ExpectedStatus: []int{http.StatusNotFound}},
+	}, true)
+
+	assert.Equal(t, []string{"health", "ready", "missing"}, m.HealthCheckNames())
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	for i, name := range []string{"health", "ready", "missing"} {
+		assert.Equal(t, name, results[i].Name)
+		assert.Equal(t, OK, results[i].Status, results[i].Error)
+	}
+
+	results, err = m.Check(context.Background(), "ready")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, OK, results[0].Status)
+
+	// Unknown check.
+	_, err = m.Check(context.Background(), "unknown")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+
+	// Disabled.
+	m = NewHTTPModule(s.Client(), nil, false)
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, Deactivated, results[0].Status)
+}
+
+func TestHTTPModuleFailures(t *testing.T) {
+	var s = newHTTPTestServer()
+	defer s.Close()
+
+	var tsts = []struct {
+		check HTTPCheck
+		err   string
+	}{
+		{HTTPCheck{URL: s.URL + "/missing"}, "invalid status code: 404"},
+		{HTTPCheck{URL: s.URL + "/health", ExpectedStatus: []int{http.StatusNoContent}}, "invalid status code: 200"},
+		{HTTPCheck{URL: s.URL + "/ready", Method: http.MethodHead}, "invalid status code: 401"},
+		{HTTPCheck{URL: s.URL + "/health", BodyContains: "fail"}, "response should contain 'fail'"},
+		{HTTPCheck{URL: s.URL + "/health", BodyRegexp: regexp.MustCompile(`^ok$`)}, "response should match '^ok$'"},
+		{HTTPCheck{URL: s.URL + "/health", JSONPath: "checks.1.healthy"}, "json path 'checks.1.healthy' not found"},
+		{HTTPCheck{URL: s.URL + "/health", JSONPath: "status", JSONValue: "fail"}, "json path 'status' should be 'fail' but is: pass"},
+		{HTTPCheck{URL: s.URL + "/ready", Method: http.MethodHead, Headers: map[string]string{"Authorization": "Bearer token"}, JSONPath: "status"}, "could not decode json response"},
+		{HTTPCheck{URL: s.URL + "/slow", Timeout: 10 * time.Millisecond}, ErrTimeout.Error()},
+		{HTTPCheck{URL: "http://127.0.0.1:0"}, "could not query http://127.0.0.1:0"},
+	}
+
+	for _, tst := range tsts {
+		tst.check.Name = "check"
+		var m = NewHTTPModule(s.Client(), []HTTPCheck{tst.check}, true)
+		var results, err = m.Check(context.Background(), "check")
+		assert.Nil(t, err)
+		assert.Equal(t, KO, results[0].Status)
+		assert.Contains(t, results[0].Error, tst.err)
+	}
+}
+
+type getOnlyClient struct {
+	client *http.Client
+}
+
+func (c *getOnlyClient) Get(url string) (*http.Response, error) {
+	return c.client.Get(url)
+}
+
+func TestHTTPModuleGetOnlyClient(t *testing.T) {
+	var s = newHTTPTestServer()
+	defer s.Close()
+
+	var m = NewHTTPModule(&getOnlyClient{s.Client()}, []HTTPCheck{
+		{Name: "health", URL: s.URL + "/health"},
+		{Name: "ready", URL: s.URL + "/ready", Method: http.MethodHead},
+	}, true)
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+	assert.Equal(t, KO, results[1].Status)
+	assert.Contains(t, results[1].Error, "cannot issue HEAD requests")
+}
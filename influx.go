@@ -4,74 +4,234 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	influx "github.com/influxdata/influxdb1-client/v2"
 	"github.com/pkg/errors"
 )
 
-// NewInfluxModule returns the influx health module.
-func NewInfluxModule(influx InfluxClient, enabled bool) *InfluxModule {
-	return &InfluxModule{
+// InfluxOption is an option of the influx module.
+type InfluxOption func(*InfluxModule)
+
+// WithInfluxWriteCheck enables the "write" check, that writes a health point in the measurement of the
+// database and queries it back. The points are not deleted, the retention policy of the database should
+// limit their number.
+func WithInfluxWriteCheck(database, measurement string) InfluxOption {
+	return func(m *InfluxModule) {
+		m.database = database
+		m.measurement = measurement
+	}
+}
+
+// InfluxRetention is an expected influx database, or bucket for influx 2.x, and its retention.
+type InfluxRetention struct {
+	// Name is the name of the database or bucket.
+	Name string
+	// Duration is the expected retention of the bucket, or of the default retention policy of the database.
+	// Zero disables the retention check.
+	Duration time.Duration
+}
+
+// WithInfluxDatabases enables the "databases" check, that verifies that the databases exist and that their
+// default retention policy is as expected. The check is KO when a database is missing, and Degraded when a
+// retention differs.
+func WithInfluxDatabases(databases ...InfluxRetention) InfluxOption {
+	return func(m *InfluxModule) {
+		m.databases = databases
+	}
+}
+
+// NewInfluxModule returns the influx health module. The "ping" check is always executed, the "write" and
+// "databases" checks when they are enabled by the options.
+func NewInfluxModule(influx InfluxClient, enabled bool, options ...InfluxOption) *InfluxModule {
+	var m = &InfluxModule{
 		influx:  influx,
 		enabled: enabled,
 	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
 }
 
 // InfluxModule is the health check module for influx.
 type InfluxModule struct {
-	influx  InfluxClient
-	enabled bool
+	influx      InfluxClient
+	enabled     bool
+	database    string
+	measurement string
+	databases   []InfluxRetention
 }
 
+// defaultInfluxPingTimeout is the influx ping timeout when the context has no deadline.
+const defaultInfluxPingTimeout = 5 * time.Second
+
 // InfluxClient is the interface of the influx client.
 type InfluxClient interface {
 	Ping(timeout time.Duration) (time.Duration, string, error)
+	Write(bp influx.BatchPoints) error
+	Query(q influx.Query) (*influx.Response, error)
 }
 
-type influxReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// HealthCheckNames returns the names of the influx health checks.
+func (m *InfluxModule) HealthCheckNames() []string {
+	var names = []string{"ping"}
+	if m.measurement != "" {
+		names = append(names, "write")
+	}
+	if len(m.databases) > 0 {
+		names = append(names, "databases")
+	}
+	return names
 }
 
 // HealthCheck executes the desired influx health check.
-func (m *InfluxModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+func (m *InfluxModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired influx health check and returns its results.
+func (m *InfluxModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]influxReport{{Name: "influx", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("influx"), nil
+	}
+
+	var checks = map[string]func(context.Context) CheckResult{
+		"ping":      m.influxPing,
+		"write":     m.influxWrite,
+		"databases": m.influxDatabases,
 	}
 
-	var reports []influxReport
-	switch name {
-	case "":
-		reports = append(reports, m.influxPing())
-	case "ping":
-		reports = append(reports, m.influxPing())
-	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown influx health check name: %v", name))
+	return runNamedChecks(ctx, name, m.HealthCheckNames(), checks)
+}
+
+func (m *InfluxModule) influxPing(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "ping", func(ctx context.Context) (map[string]interface{}, error) {
+		var latency, version, err = m.influx.Ping(influxPingTimeout(ctx))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not ping influx")
+		}
+		return map[string]interface{}{
+			"version": version,
+			"latency": latency.String(),
+		}, nil
+	})
+}
+
+func (m *InfluxModule) influxWrite(ctx context.Context) CheckResult {
+	return runCheck(ctx, "write", func(ctx context.Context) error {
+		var (
+			now   = time.Now()
+			id    = fmt.Sprintf("healthcheck-%d", now.UnixNano())
+			value = now.UnixNano()
+		)
+
+		var bp, err = influx.NewBatchPoints(influx.BatchPointsConfig{Database: m.database, Precision: "ns"})
+		if err != nil {
+			return errors.Wrap(err, "write failed")
+		}
+		var point *influx.Point
+		// The id is a field, not a tag: a unique tag would create a new series at each check.
+		point, err = influx.NewPoint(m.measurement, map[string]string{"check": "write"}, map[string]interface{}{"id": id, "value": value}, now)
+		if err != nil {
+			return errors.Wrap(err, "write failed")
+		}
+		bp.AddPoint(point)
+
+		if err = m.influx.Write(bp); err != nil {
+			return errors.Wrap(err, "write failed")
+		}
+
+		var read string
+		read, err = m.readValue(id)
+		if err != nil {
+			return errors.Wrap(err, "read failed")
+		}
+		if read != strconv.FormatInt(value, 10) {
+			return errors.Errorf("read failed: value should be '%d' but is '%s'", value, read)
+		}
+		return nil
+	})
+}
+
+func (m *InfluxModule) readValue(id string) (string, error) {
+	var query = influx.NewQueryWithParameters(fmt.Sprintf(`SELECT "value" FROM %q WHERE "id" = $id`, m.measurement), m.database, "ns",
+		map[string]interface{}{"id": id})
+
+	var resp, err = m.influx.Query(query)
+	if err == nil {
+		err = resp.Error()
+	}
+	if err != nil {
+		return "", err
 	}
 
-	return json.MarshalIndent(reports, "", "  ")
+	// The values are the time and the value of the point.
+	for _, r := range resp.Results {
+		for _, s := range r.Series {
+			for _, v := range s.Values {
+				if len(v) == 2 {
+					return fmt.Sprint(v[1]), nil
+				}
+			}
+		}
+	}
+	return "", errors.New("point not found")
 }
 
-func (m *InfluxModule) influxPing() influxReport {
-	var name = "ping"
-	var status = OK
+func (m *InfluxModule) influxDatabases(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "databases", func(ctx context.Context) (map[string]interface{}, error) {
+		var details = map[string]interface{}{}
+		var mismatches []string
+		for _, db := range m.databases {
+			var retention, err = m.defaultRetention(db.Name)
+			if err != nil {
+				return details, errors.Wrapf(err, "could not get retention policies of database %s", db.Name)
+			}
+			details[db.Name] = retention.String()
 
-	var now = time.Now()
-	var _, _, err = m.influx.Ping(5 * time.Second)
-	var duration = time.Since(now)
+			if db.Duration != 0 && retention != db.Duration {
+				mismatches = append(mismatches, fmt.Sprintf("%s retention should be %s but is %s", db.Name, db.Duration, retention))
+			}
+		}
+
+		if len(mismatches) > 0 {
+			return details, degraded(errors.New(strings.Join(mismatches, ", ")))
+		}
+		return details, nil
+	})
+}
 
+// defaultRetention returns the duration of the default retention policy of the database, zero if infinite.
+func (m *InfluxModule) defaultRetention(database string) (time.Duration, error) {
+	var resp, err = m.influx.Query(influx.NewQuery(fmt.Sprintf("SHOW RETENTION POLICIES ON %q", database), database, ""))
+	if err == nil {
+		err = resp.Error()
+	}
 	if err != nil {
-		status = KO
-		err = errors.Wrap(err, "could not ping influx")
+		return 0, err
+	}
+
+	// The columns are name, duration, shardGroupDuration, replicaN and default.
+	for _, r := range resp.Results {
+		for _, s := range r.Series {
+			for _, v := range s.Values {
+				if len(v) == 5 && v[4] == true {
+					return time.ParseDuration(fmt.Sprint(v[1]))
+				}
+			}
+		}
 	}
+	return 0, errors.New("no default retention policy")
+}
 
-	return influxReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
+// influxPingTimeout returns the time left before the deadline of the context, or the default timeout if
+// there is no deadline.
+func influxPingTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
 	}
+	return defaultInfluxPingTimeout
 }
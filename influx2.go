@@ -0,0 +1,213 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Influx2Option is an option of the influx 2.x module.
+type Influx2Option func(*Influx2Module)
+
+// WithInflux2Buckets enables the "buckets" check, that verifies that the buckets of the organization exist
+// and that their retention is as expected. The check is KO when a bucket is missing, and Degraded when a
+// retention differs. The API token must be allowed to read the buckets.
+func WithInflux2Buckets(org, token string, buckets ...InfluxRetention) Influx2Option {
+	return func(m *Influx2Module) {
+		m.org = org
+		m.token = token
+		m.buckets = buckets
+	}
+}
+
+// NewInflux2Module returns the influx 2.x health module, for the influx API at the address, e.g.
+// "http://influx:8086". The "health" and "ready" checks are always executed, the "buckets" check when it
+// is enabled by the options.
+func NewInflux2Module(httpClient HTTPClient, address string, enabled bool, options ...Influx2Option) *Influx2Module {
+	var m = &Influx2Module{
+		httpClient: httpClient,
+		address:    strings.TrimSuffix(address, "/"),
+		enabled:    enabled,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// Influx2Module is the health check module for influx 2.x.
+type Influx2Module struct {
+	httpClient HTTPClient
+	address    string
+	enabled    bool
+	org        string
+	token      string
+	buckets    []InfluxRetention
+}
+
+// HealthCheckNames returns the names of the influx 2.x health checks.
+func (m *Influx2Module) HealthCheckNames() []string {
+	var names = []string{"health", "ready"}
+	if len(m.buckets) > 0 {
+		names = append(names, "buckets")
+	}
+	return names
+}
+
+// HealthCheck executes the desired influx 2.x health check.
+func (m *Influx2Module) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired influx 2.x health check and returns its results.
+func (m *Influx2Module) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	if !m.enabled {
+		return deactivated("influx"), nil
+	}
+
+	var checks = map[string]func(context.Context) CheckResult{
+		"health":  m.influxHealth,
+		"ready":   m.influxReady,
+		"buckets": m.influxBuckets,
+	}
+
+	return runNamedChecks(ctx, name, m.HealthCheckNames(), checks)
+}
+
+func (m *Influx2Module) influxHealth(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "health", func(ctx context.Context) (map[string]interface{}, error) {
+		// The status code is 503 when influx is not healthy, the body still describes the health.
+		var health struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Version string `json:"version"`
+		}
+		if err := m.get(ctx, "/health", nil, &health, http.StatusOK, http.StatusServiceUnavailable); err != nil {
+			return nil, errors.Wrap(err, "could not get influx health")
+		}
+
+		var details = map[string]interface{}{
+			"status":  health.Status,
+			"version": health.Version,
+		}
+		if health.Status != "pass" {
+			return details, errors.Errorf("influx health status is %s: %s", health.Status, health.Message)
+		}
+		return details, nil
+	})
+}
+
+func (m *Influx2Module) influxReady(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "ready", func(ctx context.Context) (map[string]interface{}, error) {
+		var ready struct {
+			Status string `json:"status"`
+			Up     string `json:"up"`
+		}
+		if err := m.get(ctx, "/ready", nil, &ready, http.StatusOK, http.StatusServiceUnavailable); err != nil {
+			return nil, errors.Wrap(err, "could not get influx readiness")
+		}
+
+		var details = map[string]interface{}{
+			"status": ready.Status,
+			"up":     ready.Up,
+		}
+		if ready.Status != "ready" {
+			return details, errors.Errorf("influx status is %s", ready.Status)
+		}
+		return details, nil
+	})
+}
+
+func (m *Influx2Module) influxBuckets(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "buckets", func(ctx context.Context) (map[string]interface{}, error) {
+		var details = map[string]interface{}{}
+		var mismatches []string
+		for _, b := range m.buckets {
+			var retention, err = m.bucketRetention(ctx, b.Name)
+			if err != nil {
+				return details, errors.Wrapf(err, "could not get bucket %s", b.Name)
+			}
+			details[b.Name] = retention.String()
+
+			if b.Duration != 0 && retention != b.Duration {
+				mismatches = append(mismatches, fmt.Sprintf("%s retention should be %s but is %s", b.Name, b.Duration, retention))
+			}
+		}
+
+		if len(mismatches) > 0 {
+			return details, degraded(errors.New(strings.Join(mismatches, ", ")))
+		}
+		return details, nil
+	})
+}
+
+// bucketRetention returns the retention of the bucket, zero if infinite.
+func (m *Influx2Module) bucketRetention(ctx context.Context, name string) (time.Duration, error) {
+	var resp struct {
+		Buckets []struct {
+			Name           string `json:"name"`
+			RetentionRules []struct {
+				Type         string `json:"type"`
+				EverySeconds int64  `json:"everySeconds"`
+			} `json:"retentionRules"`
+		} `json:"buckets"`
+	}
+	var query = url.Values{"org": {m.org}, "name": {name}}
+	if err := m.get(ctx, "/api/v2/buckets", query, &resp, http.StatusOK); err != nil {
+		return 0, err
+	}
+
+	for _, b := range resp.Buckets {
+		if b.Name != name {
+			continue
+		}
+		for _, r := range b.RetentionRules {
+			if r.Type == "expire" {
+				return time.Duration(r.EverySeconds) * time.Second, nil
+			}
+		}
+		return 0, nil
+	}
+	return 0, errors.New("bucket not found")
+}
+
+// get queries the path of the influx API and decodes the JSON response.
+func (m *Influx2Module) get(ctx context.Context, path string, query url.Values, v interface{}, expectedStatus ...int) error {
+	var c = HTTPCheck{
+		URL:            m.address + path,
+		ExpectedStatus: expectedStatus,
+	}
+	if len(query) > 0 {
+		c.URL += "?" + query.Encode()
+	}
+	if m.token != "" {
+		c.Headers = map[string]string{"Authorization": "Token " + m.token}
+	}
+
+	var res, err = c.do(ctx, m.httpClient)
+	if err != nil {
+		return errors.Wrapf(err, "could not query %s", c.URL)
+	}
+	defer res.Body.Close()
+
+	if !c.expectedStatus(res.StatusCode) {
+		return errors.Errorf("%s returned invalid status code: %v", c.URL, res.StatusCode)
+	}
+
+	var body []byte
+	body, err = io.ReadAll(io.LimitReader(res.Body, maxHTTPBodySize))
+	if err != nil {
+		return errors.Wrap(err, "could not read response body")
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return errors.Wrap(err, "could not decode json response")
+	}
+	return nil
+}
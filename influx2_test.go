@@ -0,0 +1,173 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+// influx2Server is a stand-in of the influx 2.x API.
+type influx2Server struct {
+	health      int
+	ready       int
+	healthBody  string
+	readyStatus string
+	// buckets are the retention of the buckets of the "cloudtrust" organization, in seconds.
+	buckets map[string]int64
+}
+
+func (s *influx2Server) start(t *testing.T) *httptest.Server {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.health)
+		w.Write([]byte(s.healthBody))
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.ready)
+		fmt.Fprintf(w, `{"status":%q,"started":"2024-06-10T09:12:51Z","up":"26h3m12s"}`, s.readyStatus)
+	})
+	mux.HandleFunc("/api/v2/buckets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"unauthorized","message":"unauthorized access"}`))
+			return
+		}
+		if r.URL.Query().Get("org") != "cloudtrust" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"not found","message":"organization not found"}`))
+			return
+		}
+
+		var buckets = []interface{}{}
+		var name = r.URL.Query().Get("name")
+		if every, ok := s.buckets[name]; ok {
+			var rules = []interface{}{}
+			if every > 0 {
+				rules = append(rules, map[string]interface{}{"type": "expire", "everySeconds": every, "shardGroupDurationSeconds": 86400})
+			}
+			buckets = append(buckets, map[string]interface{}{"id": "0a4bd1e8c2f3d9a1", "name": name, "retentionRules": rules})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"buckets": buckets})
+	})
+
+	var server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newInflux2Server() *influx2Server {
+	return &influx2Server{
+		health:      http.StatusOK,
+		ready:       http.StatusOK,
+		healthBody:  `{"name":"influxdb","message":"ready for queries and writes","status":"pass","checks":[],"version":"v2.7.6","commit":"3c58c06206"}`,
+		readyStatus: "ready",
+		buckets:     map[string]int64{"metrics": 7 * 24 * 3600, "events": 0},
+	}
+}
+
+func TestInflux2Module(t *testing.T) {
+	var influx = newInflux2Server()
+	var server = influx.start(t)
+
+	var m = NewInflux2Module(server.Client(), server.URL+"/", true, WithInflux2Buckets("cloudtrust", "secret",
+		InfluxRetention{Name: "metrics", Duration: 7 * 24 * time.Hour}, InfluxRetention{Name: "events"}))
+	assert.Equal(t, []string{"health", "ready", "buckets"}, m.HealthCheckNames())
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	for i, name := range []string{"health", "ready", "buckets"} {
+		assert.Equal(t, name, results[i].Name)
+		assert.Equal(t, OK, results[i].Status, results[i].Error)
+	}
+	assert.Equal(t, map[string]interface{}{"status": "pass", "version": "v2.7.6"}, results[0].Details)
+	assert.Equal(t, map[string]interface{}{"status": "ready", "up": "26h3m12s"}, results[1].Details)
+	assert.Equal(t, map[string]interface{}{"metrics": "168h0m0s", "events": "0s"}, results[2].Details)
+
+	// The report is valid json.
+	var report json.RawMessage
+	report, err = m.HealthCheck(context.Background(), "health")
+	assert.Nil(t, err)
+	assert.Contains(t, string(report), `"version": "v2.7.6"`)
+
+	// The buckets check is unknown when it is not enabled.
+	m = NewInflux2Module(server.Client(), server.URL, true)
+	assert.Equal(t, []string{"health", "ready"}, m.HealthCheckNames())
+	_, err = m.Check(context.Background(), "buckets")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+
+	// Disabled.
+	m = NewInflux2Module(server.Client(), server.URL, false)
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "influx", results[0].Name)
+	assert.Equal(t, Deactivated, results[0].Status)
+}
+
+func TestInflux2ModuleFailures(t *testing.T) {
+	var influx = newInflux2Server()
+	var server = influx.start(t)
+
+	var m = NewInflux2Module(server.Client(), server.URL, true, WithInflux2Buckets("cloudtrust", "secret",
+		InfluxRetention{Name: "metrics", Duration: 30 * 24 * time.Hour}, InfluxRetention{Name: "events", Duration: time.Hour}))
+
+	// Health.
+	influx.health = http.StatusServiceUnavailable
+	influx.healthBody = `{"name":"influxdb","message":"bolt: database not open","status":"fail","checks":[],"version":"v2.7.6"}`
+	var results, err = m.Check(context.Background(), "health")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "influx health status is fail: bolt: database not open", results[0].Error)
+	assert.Equal(t, "fail", results[0].Details["status"])
+
+	influx.health = http.StatusInternalServerError
+	results, _ = m.Check(context.Background(), "health")
+	assert.Contains(t, results[0].Error, "returned invalid status code: 500")
+
+	influx.health = http.StatusOK
+	influx.healthBody = "ok"
+	results, _ = m.Check(context.Background(), "health")
+	assert.Contains(t, results[0].Error, "could not decode json response")
+
+	// Readiness.
+	influx.ready = http.StatusServiceUnavailable
+	influx.readyStatus = "starting"
+	results, _ = m.Check(context.Background(), "ready")
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "influx status is starting", results[0].Error)
+
+	// Retentions differ.
+	results, _ = m.Check(context.Background(), "buckets")
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Equal(t, "metrics retention should be 720h0m0s but is 168h0m0s, events retention should be 1h0m0s but is 0s", results[0].Error)
+	assert.Equal(t, map[string]interface{}{"metrics": "168h0m0s", "events": "0s"}, results[0].Details)
+
+	// Missing bucket.
+	delete(influx.buckets, "events")
+	results, _ = m.Check(context.Background(), "buckets")
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "could not get bucket events: bucket not found", results[0].Error)
+
+	// Unauthorized.
+	m = NewInflux2Module(server.Client(), server.URL, true, WithInflux2Buckets("cloudtrust", "invalid", InfluxRetention{Name: "metrics"}))
+	results, _ = m.Check(context.Background(), "buckets")
+	assert.Equal(t, KO, results[0].Status)
+	assert.Contains(t, results[0].Error, "returned invalid status code: 401")
+
+	// Unreachable.
+	server.Close()
+	results, _ = m.Check(context.Background(), "")
+	for _, r := range results {
+		assert.Equal(t, KO, r.Status)
+		assert.Contains(t, r.Error, "could not query")
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
 	. "github.com/cloudtrust/common-healthcheck"
 	mock "github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/influxdata/influxdb1-client/models"
+	influx "github.com/influxdata/influxdb1-client/v2"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -21,10 +24,11 @@ func init() {
 }
 
 type influxReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+	Name     string                 `json:"name"`
+	Status   string                 `json:"status"`
+	Duration string                 `json:"duration,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
 }
 
 func TestInfluxDisabled(t *testing.T) {
@@ -62,7 +66,7 @@ func TestInfluxPing(t *testing.T) {
 		d       = 1 * time.Second
 	)
 
-	mockInflux.EXPECT().Ping(5*time.Second).Return(d, "", nil).Times(1)
+	mockInflux.EXPECT().Ping(5*time.Second).Return(d, "1.8.10", nil).Times(1)
 	var jsonReport, err = m.HealthCheck(context.Background(), "ping")
 	assert.Nil(t, err)
 
@@ -75,6 +79,7 @@ func TestInfluxPing(t *testing.T) {
 	assert.Equal(t, "OK", r.Status)
 	assert.NotZero(t, r.Duration)
 	assert.Zero(t, r.Error)
+	assert.Equal(t, map[string]interface{}{"version": "1.8.10", "latency": "1s"}, r.Details)
 }
 
 func TestInfluxAllChecks(t *testing.T) {
@@ -140,8 +145,171 @@ func TestInfluxUnkownHealthCheck(t *testing.T) {
 		m               = NewInfluxModule(mockInflux, enabled)
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
-	}
-	assert.Panics(t, f)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+}
+
+func TestInfluxPingTimeout(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockInflux = mock.NewInfluxClient(mockCtrl)
+
+	var (
+		enabled     = true
+		m           = NewInfluxModule(mockInflux, enabled)
+		ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+	)
+	defer cancel()
+
+	// The ping timeout is the time left before the deadline.
+	mockInflux.EXPECT().Ping(gomock.Any()).DoAndReturn(func(timeout time.Duration) (time.Duration, string, error) {
+		assert.True(t, timeout > 0 && timeout <= 1*time.Second)
+		return 0, "", nil
+	}).Times(1)
+	var results, err = m.Check(ctx, "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+}
+
+// influxPoint returns the response of a query of the point, read back from the batch.
+func influxPoint(bp influx.BatchPoints) *influx.Response {
+	var p = bp.Points()[0]
+	var fields, _ = p.Fields()
+	return &influx.Response{Results: []influx.Result{{Series: []models.Row{{
+		Name:    p.Name(),
+		Columns: []string{"time", "value"},
+		Values:  [][]interface{}{{json.Number(fmt.Sprint(p.UnixNano())), json.Number(fmt.Sprint(fields["value"]))}},
+	}}}}}
+}
+
+func TestInfluxWrite(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockInflux = mock.NewInfluxClient(mockCtrl)
+
+	var m = NewInfluxModule(mockInflux, true, WithInfluxWriteCheck("metrics", "health"))
+	assert.Equal(t, []string{"ping", "write"}, m.HealthCheckNames())
+
+	var written influx.BatchPoints
+	gomock.InOrder(
+		mockInflux.EXPECT().Write(gomock.Any()).DoAndReturn(func(bp influx.BatchPoints) error {
+			assert.Equal(t, "metrics", bp.Database())
+			assert.Equal(t, "health", bp.Points()[0].Name())
+			assert.Equal(t, map[string]string{"check": "write"}, bp.Points()[0].Tags())
+			var fields, _ = bp.Points()[0].Fields()
+			assert.True(t, strings.HasPrefix(fields["id"].(string), "healthcheck-"))
+			written = bp
+			return nil
+		}).Times(1),
+		mockInflux.EXPECT().Query(gomock.Any()).DoAndReturn(func(q influx.Query) (*influx.Response, error) {
+			assert.Equal(t, `SELECT "value" FROM "health" WHERE "id" = $id`, q.Command)
+			assert.Equal(t, "metrics", q.Database)
+			var fields, _ = written.Points()[0].Fields()
+			assert.Equal(t, fields["id"], q.Parameters["id"])
+			return influxPoint(written), nil
+		}).Times(1),
+	)
+	var results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Equal(t, "write", results[0].Name)
+	assert.Equal(t, OK, results[0].Status, results[0].Error)
+
+	// Write failure.
+	mockInflux.EXPECT().Write(gomock.Any()).Return(fmt.Errorf("database not found: \"metrics\"")).Times(1)
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "write failed: database not found: \"metrics\"", results[0].Error)
+
+	// Read failures.
+	mockInflux.EXPECT().Write(gomock.Any()).Return(nil).Times(1)
+	mockInflux.EXPECT().Query(gomock.Any()).Return(nil, fmt.Errorf("timeout")).Times(1)
+	results, _ = m.Check(context.Background(), "write")
+	assert.Equal(t, "read failed: timeout", results[0].Error)
+
+	mockInflux.EXPECT().Write(gomock.Any()).Return(nil).Times(1)
+	mockInflux.EXPECT().Query(gomock.Any()).Return(&influx.Response{Err: "authorization failed"}, nil).Times(1)
+	results, _ = m.Check(context.Background(), "write")
+	assert.Equal(t, "read failed: authorization failed", results[0].Error)
+
+	mockInflux.EXPECT().Write(gomock.Any()).Return(nil).Times(1)
+	mockInflux.EXPECT().Query(gomock.Any()).Return(&influx.Response{Results: []influx.Result{{}}}, nil).Times(1)
+	results, _ = m.Check(context.Background(), "write")
+	assert.Equal(t, "read failed: point not found", results[0].Error)
+
+	mockInflux.EXPECT().Write(gomock.Any()).Return(nil).Times(1)
+	mockInflux.EXPECT().Query(gomock.Any()).Return(&influx.Response{Results: []influx.Result{{Series: []models.Row{{
+		Values: [][]interface{}{{json.Number("0"), json.Number("42")}},
+	}}}}}, nil).Times(1)
+	results, _ = m.Check(context.Background(), "write")
+	assert.Contains(t, results[0].Error, "read failed: value should be")
+
+	// The write check is unknown when it is not enabled.
+	m = NewInfluxModule(mockInflux, true)
+	_, err = m.Check(context.Background(), "write")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+}
+
+// influxRetentionPolicies returns the response of SHOW RETENTION POLICIES.
+func influxRetentionPolicies(values ...[]interface{}) *influx.Response {
+	return &influx.Response{Results: []influx.Result{{Series: []models.Row{{
+		Columns: []string{"name", "duration", "shardGroupDuration", "replicaN", "default"},
+		Values:  values,
+	}}}}}
+}
+
+func TestInfluxDatabases(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockInflux = mock.NewInfluxClient(mockCtrl)
+
+	var m = NewInfluxModule(mockInflux, true, WithInfluxDatabases(
+		InfluxRetention{Name: "metrics", Duration: 168 * time.Hour}, InfluxRetention{Name: "events"}))
+	assert.Equal(t, []string{"ping", "databases"}, m.HealthCheckNames())
+
+	var (
+		weekly   = []interface{}{"weekly", "168h0m0s", "24h0m0s", json.Number("1"), true}
+		autogen  = []interface{}{"autogen", "0s", "168h0m0s", json.Number("1"), true}
+		monthly  = []interface{}{"monthly", "720h0m0s", "24h0m0s", json.Number("1"), false}
+		policies = func(db string, resp *influx.Response) *gomock.Call {
+			return mockInflux.EXPECT().Query(gomock.Any()).DoAndReturn(func(q influx.Query) (*influx.Response, error) {
+				assert.Equal(t, fmt.Sprintf("SHOW RETENTION POLICIES ON %q", db), q.Command)
+				return resp, nil
+			}).Times(1)
+		}
+	)
+
+	gomock.InOrder(
+		policies("metrics", influxRetentionPolicies(monthly, weekly)),
+		policies("events", influxRetentionPolicies(autogen)),
+	)
+	var results, err = m.Check(context.Background(), "databases")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status, results[0].Error)
+	assert.Equal(t, map[string]interface{}{"metrics": "168h0m0s", "events": "0s"}, results[0].Details)
+
+	// Retention differs.
+	gomock.InOrder(
+		policies("metrics", influxRetentionPolicies(autogen, monthly)),
+		policies("events", influxRetentionPolicies(weekly)),
+	)
+	results, _ = m.Check(context.Background(), "databases")
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Equal(t, "metrics retention should be 168h0m0s but is 0s", results[0].Error)
+
+	// Missing database.
+	mockInflux.EXPECT().Query(gomock.Any()).Return(&influx.Response{Results: []influx.Result{{Err: "database not found: metrics"}}}, nil).Times(1)
+	results, _ = m.Check(context.Background(), "databases")
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "could not get retention policies of database metrics: database not found: metrics", results[0].Error)
+
+	mockInflux.EXPECT().Query(gomock.Any()).Return(influxRetentionPolicies(monthly), nil).Times(1)
+	results, _ = m.Check(context.Background(), "databases")
+	assert.Equal(t, "could not get retention policies of database metrics: no default retention policy", results[0].Error)
+
+	mockInflux.EXPECT().Query(gomock.Any()).Return(nil, fmt.Errorf("connection refused")).Times(1)
+	results, _ = m.Check(context.Background(), "databases")
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "could not get retention policies of database metrics: connection refused", results[0].Error)
 }
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// MakeHealthCheckerInstrumentingMW makes an instrumenting middleware for the health check modules. For each
+// executed check, it observes the duration in seconds in the histogram, increments the counter and sets
+// the gauge of the status of the check to 1 and the gauges of the other statuses to 0. When the health
+// check fails with an error, the counter is incremented with the status KO and the requested name as check.
+// The histogram is labelled with "module" and "check", the counter and the gauge with "module", "check"
+// and "status", so that successes and failures can be told apart. The module label is set for the results
+// of a Registry, so the middleware is best installed around the Registry.
+func MakeHealthCheckerInstrumentingMW(duration metrics.Histogram, checks metrics.Counter, status metrics.Gauge) func(HealthChecker) HealthChecker {
+	return func(next HealthChecker) HealthChecker {
+		return &healthCheckerInstrumentingMW{
+			duration: duration,
+			checks:   checks,
+			status:   status,
+			next:     next,
+		}
+	}
+}
+
+type healthCheckerInstrumentingMW struct {
+	duration metrics.Histogram
+	checks   metrics.Counter
+	status   metrics.Gauge
+	next     HealthChecker
+}
+
+// HealthCheck implements HealthChecker.
+func (m *healthCheckerInstrumentingMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var report, err = m.next.HealthCheck(ctx, name)
+	if err != nil {
+		m.recordError(name)
+		return nil, err
+	}
+
+	if results, err := decodeReport(report); err == nil {
+		m.record(results)
+	}
+	return report, nil
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (m *healthCheckerInstrumentingMW) HealthCheckNames() []string {
+	return healthCheckNames(m.next)
+}
+
+// Check implements Checker.
+func (m *healthCheckerInstrumentingMW) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var results, err = check(ctx, m.next, name)
+	if err != nil {
+		m.recordError(name)
+		return nil, err
+	}
+
+	m.record(results)
+	return results, nil
+}
+
+func (m *healthCheckerInstrumentingMW) record(results []CheckResult) {
+	for _, r := range results {
+		if r.Status != Deactivated {
+			m.duration.With("module", r.Module, "check", r.Name).Observe(r.Duration.Seconds())
+		}
+		m.checks.With("module", r.Module, "check", r.Name, "status", r.Status.String()).Add(1)
+		for s := Status(0); int(s) < len(_Status_index)-1; s++ {
+			var value float64
+			if s == r.Status {
+				value = 1
+			}
+			m.status.With("module", r.Module, "check", r.Name, "status", s.String()).Set(value)
+		}
+	}
+}
+
+// recordError counts the failure of the health check name, that returned an error instead of results.
+func (m *healthCheckerInstrumentingMW) recordError(name string) {
+	m.checks.With("module", "", "check", name, "status", KO.String()).Add(1)
+}
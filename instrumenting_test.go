@@ -0,0 +1,152 @@
+package common_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// The generic metrics returned by With do not share their values with the parent metric, so the labelled
+// metrics are kept to be inspected by the tests.
+type labelledCounters struct {
+	mutex    sync.Mutex
+	counters map[string]metrics.Counter
+}
+
+func (c *labelledCounters) With(labelValues ...string) metrics.Counter {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var key = strings.Join(labelValues, ",")
+	if _, ok := c.counters[key]; !ok {
+		c.counters[key] = generic.NewCounter("checks").With(labelValues...)
+	}
+	return c.counters[key]
+}
+
+func (c *labelledCounters) Add(delta float64) {}
+
+func (c *labelledCounters) value(labelValues ...string) float64 {
+	var counter, ok = c.counters[strings.Join(labelValues, ",")]
+	if !ok {
+		return 0
+	}
+	return counter.(*generic.Counter).Value()
+}
+
+type labelledGauges struct {
+	mutex  sync.Mutex
+	gauges map[string]metrics.Gauge
+}
+
+func (g *labelledGauges) With(labelValues ...string) metrics.Gauge {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var key = strings.Join(labelValues, ",")
+	if _, ok := g.gauges[key]; !ok {
+		g.gauges[key] = generic.NewGauge("status").With(labelValues...)
+	}
+	return g.gauges[key]
+}
+
+func (g *labelledGauges) Set(value float64) {}
+func (g *labelledGauges) Add(delta float64) {}
+
+type labelledHistograms struct {
+	mutex      sync.Mutex
+	histograms map[string]metrics.Histogram
+}
+
+func (h *labelledHistograms) With(labelValues ...string) metrics.Histogram {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var key = strings.Join(labelValues, ",")
+	if _, ok := h.histograms[key]; !ok {
+		h.histograms[key] = generic.NewHistogram("duration", 10).With(labelValues...)
+	}
+	return h.histograms[key]
+}
+
+func (h *labelledHistograms) Observe(value float64) {}
+
+func TestInstrumentingMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+	var mockInflux = mock.NewInfluxClient(mockCtrl)
+
+	var (
+		duration = &labelledHistograms{histograms: map[string]metrics.Histogram{}}
+		checks   = &labelledCounters{counters: map[string]metrics.Counter{}}
+		status   = &labelledGauges{gauges: map[string]metrics.Gauge{}}
+		r        = NewRegistry()
+	)
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	r.Register("influx", NewInfluxModule(mockInflux, false))
+	var m = MakeHealthCheckerInstrumentingMW(duration, checks, status)(r)
+
+	mockRedis.EXPECT().Do("PING").DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	}).Times(1)
+	mockRedis.EXPECT().Do("PING").Return(nil, fmt.Errorf("fail")).Times(2)
+
+	// JSON API.
+	var _, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+	_, err = m.HealthCheck(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+
+	// Typed API.
+	var results []CheckResult
+	results, err = m.(Checker).Check(context.Background(), "redis")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+
+	assert.Equal(t, 1.0, checks.value("module", "redis", "check", "ping", "status", "OK"))
+	assert.Equal(t, 2.0, checks.value("module", "redis", "check", "ping", "status", "KO"))
+	assert.Equal(t, 1.0, checks.value("module", "influx", "check", "influx", "status", "Deactivated"))
+
+	// One gauge per status, set to 1 for the status of the check.
+	assert.Equal(t, 1.0, status.gauges["module,redis,check,ping,status,KO"].(*generic.Gauge).Value())
+	assert.Equal(t, 0.0, status.gauges["module,redis,check,ping,status,OK"].(*generic.Gauge).Value())
+	assert.Equal(t, 0.0, status.gauges["module,redis,check,ping,status,Degraded"].(*generic.Gauge).Value())
+	assert.Equal(t, 0.0, status.gauges["module,redis,check,ping,status,Deactivated"].(*generic.Gauge).Value())
+	assert.Equal(t, 1.0, status.gauges["module,influx,check,influx,status,Deactivated"].(*generic.Gauge).Value())
+	assert.Equal(t, 0.0, status.gauges["module,influx,check,influx,status,OK"].(*generic.Gauge).Value())
+
+	// The durations of deactivated checks are not recorded.
+	assert.True(t, duration.histograms["module,redis,check,ping"].(*generic.Histogram).Quantile(1) > 0)
+	assert.NotContains(t, duration.histograms, "module,influx,check,influx")
+}
+
+func TestInstrumentingMWError(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		duration = &labelledHistograms{histograms: map[string]metrics.Histogram{}}
+		checks   = &labelledCounters{counters: map[string]metrics.Counter{}}
+		status   = &labelledGauges{gauges: map[string]metrics.Gauge{}}
+		m        = MakeHealthCheckerInstrumentingMW(duration, checks, status)(mockHealthChecker)
+	)
+
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "unknown").Return(nil, &ErrInvalidHCName{}).Times(1)
+	var _, err = m.HealthCheck(context.Background(), "unknown")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+
+	// The error is counted as a failure, without results to record.
+	assert.Equal(t, 1.0, checks.value("module", "", "check", "unknown", "status", "KO"))
+	assert.Empty(t, status.gauges)
+	assert.Empty(t, duration.histograms)
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+	"net/http"
 
 	"github.com/pkg/errors"
 )
@@ -25,58 +25,46 @@ type JaegerModule struct {
 	enabled                 bool
 }
 
-
-type jaegerReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// HealthCheckNames returns the names of the jaeger health checks.
+func (m *JaegerModule) HealthCheckNames() []string {
+	return []string{"collector"}
 }
 
 // HealthCheck executes the desired jaeger health check.
-func (m *JaegerModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+func (m *JaegerModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired jaeger health check and returns its results.
+func (m *JaegerModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]jaegerReport{{Name: "jaeger", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("jaeger"), nil
 	}
 
-	var reports []jaegerReport
+	var results []CheckResult
 	switch name {
 	case "":
-		reports = append(reports, m.jaegerCollectorPing())
+		results = runChecks(ctx, m.jaegerCollectorPing)
 	case "collector":
-		reports = append(reports, m.jaegerCollectorPing())
+		results = append(results, m.jaegerCollectorPing(ctx))
 	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown jaeger health check name: %v", name))
+		return nil, &ErrInvalidHCName{name}
 	}
 
-	return json.MarshalIndent(reports, "", "  ")
+	return results, nil
 }
 
-func (m *JaegerModule) jaegerCollectorPing() jaegerReport {
-	var name = "ping collector"
-	var status = OK
-
-	// Query jaeger collector health check URL
-	var now = time.Now()
-	var res, err = m.httpClient.Get(fmt.Sprintf("http://%s", m.collectorHealthHostPort))
-	var duration = time.Since(now)
-
-	switch {
-	case err != nil:
-		err = errors.Wrap(err, "could not query jaeger collector health check service")
-		status = KO
-	case res.StatusCode != 204:
-		err = errors.Wrapf(err, "jaeger health check service returned invalid status code: %v", res.StatusCode)
-		status = KO
-	default:
-		status = OK
+func (m *JaegerModule) jaegerCollectorPing(ctx context.Context) CheckResult {
+	var collector = HTTPCheck{
+		URL:            fmt.Sprintf("http://%s", m.collectorHealthHostPort),
+		ExpectedStatus: []int{http.StatusNoContent},
 	}
 
-	return jaegerReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
-	}
+	return runCheck(ctx, "ping collector", func(ctx context.Context) error {
+		// Query jaeger collector health check URL
+		if err := collector.execute(ctx, m.httpClient); err != nil {
+			return errors.Wrap(err, "jaeger collector health check failed")
+		}
+		return nil
+	})
 }
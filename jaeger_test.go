@@ -128,8 +128,36 @@ func TestJaegerUnkownHealthCheck(t *testing.T) {
 		m               = NewJaegerModule(s.Client(), url, enabled)
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
-	}
-	assert.Panics(t, f)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+}
+
+func TestJaegerTimeout(t *testing.T) {
+	var unblock = make(chan struct{})
+	var s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+	defer close(unblock)
+
+	var (
+		enabled     = true
+		url         = s.URL[7:] // strip http:// from URL
+		m           = NewJaegerModule(s.Client(), url, enabled)
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	)
+	defer cancel()
+
+	var results, err = m.Check(ctx, "collector")
+	assert.Nil(t, err)
+
+	var r = results[0]
+	assert.Equal(t, "ping collector", r.Name)
+	assert.Equal(t, KO, r.Status)
+	assert.Equal(t, ErrTimeout.Error(), r.Error)
 }
@@ -6,28 +6,134 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 )
 
-// MakeHealthCheckerLoggingMW makes a logging middleware for the health check modules.
-func MakeHealthCheckerLoggingMW(logger log.Logger) func(HealthChecker) HealthChecker {
+// LoggingOption is an option of the logging middleware.
+type LoggingOption func(*healthCheckerLoggingMW)
+
+// WithCorrelationIDKey sets the context key of the correlation ID. By default, the key is "correlation_id".
+func WithCorrelationIDKey(key interface{}) LoggingOption {
+	return func(m *healthCheckerLoggingMW) {
+		m.correlationIDKey = key
+	}
+}
+
+// WithCorrelationIDGenerator sets the function generating a correlation ID when there is none in the
+// context. The generated ID is added to the context given to the health checks. By default, no ID is
+// generated and the logs have no correlation ID.
+func WithCorrelationIDGenerator(generate func() string) LoggingOption {
+	return func(m *healthCheckerLoggingMW) {
+		m.generateCorrelationID = generate
+	}
+}
+
+// MakeHealthCheckerLoggingMW makes a logging middleware for the health check modules. Each health check is
+// logged with its name, overall status and duration, at level info when it is OK, warn when it is Degraded
+// and error when it is KO or returns an error. Each failed check is also logged with its error.
+func MakeHealthCheckerLoggingMW(logger log.Logger, options ...LoggingOption) func(HealthChecker) HealthChecker {
 	return func(next HealthChecker) HealthChecker {
-		return &healthCheckerLoggingMW{
-			logger: logger,
-			next:   next,
+		var m = &healthCheckerLoggingMW{
+			logger:           logger,
+			next:             next,
+			correlationIDKey: "correlation_id",
 		}
+		for _, option := range options {
+			option(m)
+		}
+		return m
 	}
 }
 
 type healthCheckerLoggingMW struct {
-	logger log.Logger
-	next   HealthChecker
+	logger                log.Logger
+	next                  HealthChecker
+	correlationIDKey      interface{}
+	generateCorrelationID func() string
 }
 
-// healthCheckLoggingMW implements HealthChecker. There must be a key "correlation_id" with a string value in the context.
+// healthCheckLoggingMW implements HealthChecker. The correlation ID is taken from the context, if any.
 func (m *healthCheckerLoggingMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
-	defer func(begin time.Time) {
-		m.logger.Log("unit", "HealthCheck", "correlation_id", ctx.Value("correlation_id").(string), "took", time.Since(begin))
-	}(time.Now())
+	var ctx2, logger = m.context(ctx)
+
+	var begin = time.Now()
+	var report, err = m.next.HealthCheck(ctx2, name)
+	var took = time.Since(begin)
+
+	if err != nil {
+		level.Error(logger).Log("name", name, "error", err.Error(), "took", took)
+		return report, err
+	}
+
+	var results, _ = decodeReport(report)
+	m.log(logger, name, reportStatus(report), results, took)
+	return report, nil
+}
+
+// Check implements Checker.
+func (m *healthCheckerLoggingMW) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var ctx2, logger = m.context(ctx)
+
+	var begin = time.Now()
+	var results, err = check(ctx2, m.next, name)
+	var took = time.Since(begin)
+
+	if err != nil {
+		level.Error(logger).Log("name", name, "error", err.Error(), "took", took)
+		return nil, err
+	}
 
-	return m.next.HealthCheck(ctx, name)
+	m.log(logger, name, AggregateStatus(results), results, took)
+	return results, nil
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (m *healthCheckerLoggingMW) HealthCheckNames() []string {
+	return healthCheckNames(m.next)
+}
+
+// context returns the context of the health check and the logger, with the correlation ID if any.
+func (m *healthCheckerLoggingMW) context(ctx context.Context) (context.Context, log.Logger) {
+	var logger = log.With(m.logger, "unit", "HealthCheck")
+
+	var corrID, ok = ctx.Value(m.correlationIDKey).(string)
+	if !ok && m.generateCorrelationID != nil {
+		corrID, ok = m.generateCorrelationID(), true
+		ctx = context.WithValue(ctx, m.correlationIDKey, corrID)
+	}
+	if ok {
+		logger = log.With(logger, "correlation_id", corrID)
+	}
+	return ctx, logger
+}
+
+// log logs the overall status of the health check, and the failed checks.
+func (m *healthCheckerLoggingMW) log(logger log.Logger, name string, status Status, results []CheckResult, took time.Duration) {
+	var statusLogger log.Logger
+	switch status {
+	case KO:
+		statusLogger = level.Error(logger)
+	case Degraded:
+		statusLogger = level.Warn(logger)
+	default:
+		statusLogger = level.Info(logger)
+	}
+	statusLogger.Log("name", name, "status", status.String(), "took", took)
+
+	if status == OK {
+		return
+	}
+	for _, r := range results {
+		var checkName = r.Name
+		if r.Module != "" {
+			checkName = r.Module + "/" + r.Name
+		}
+
+		switch r.Status {
+		case KO:
+			level.Error(logger).Log("name", name, "check", checkName, "status", r.Status.String(), "error", r.Error)
+		case Degraded:
+			level.Warn(logger).Log("name", name, "check", checkName, "status", r.Status.String(), "error", r.Error)
+		}
+	}
 }
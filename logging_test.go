@@ -1,8 +1,11 @@
 package common_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"math/rand"
 	"strconv"
 	"testing"
@@ -10,6 +13,7 @@ import (
 
 	. "github.com/cloudtrust/common-healthcheck"
 	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/go-kit/kit/log/level"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -31,17 +35,119 @@ func TestLoggingMW(t *testing.T) {
 		corrID          = strconv.FormatUint(rand.Uint64(), 10)
 		ctx             = context.WithValue(context.Background(), "correlation_id", corrID)
 		healthCheckName = "name"
-		rep             = json.RawMessage(`{"key":"value"}`)
 	)
 
-	mockHealthChecker.EXPECT().HealthCheck(ctx, healthCheckName).Return(rep, nil).Times(1)
-	mockLogger.EXPECT().Log("unit", "HealthCheck", "correlation_id", corrID, "took", gomock.Any()).Return(nil).Times(1)
+	mockHealthChecker.EXPECT().HealthCheck(ctx, healthCheckName).Return(okReport, nil).Times(1)
+	mockLogger.EXPECT().Log(level.Key(), level.InfoValue(), "unit", "HealthCheck", "correlation_id", corrID, "name", healthCheckName, "status", "OK", "took", gomock.Any()).Return(nil).Times(1)
 	m.HealthCheck(ctx, healthCheckName)
 
 	// Without correlation ID.
-	mockHealthChecker.EXPECT().HealthCheck(context.Background(), healthCheckName).Return(rep, nil).Times(1)
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), healthCheckName).Return(okReport, nil).Times(1)
+	mockLogger.EXPECT().Log(level.Key(), level.InfoValue(), "unit", "HealthCheck", "name", healthCheckName, "status", "OK", "took", gomock.Any()).Return(nil).Times(1)
 	var f = func() {
 		m.HealthCheck(context.Background(), healthCheckName)
 	}
-	assert.Panics(t, f)
+	assert.NotPanics(t, f)
+}
+
+func TestLoggingMWFailures(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		m               = MakeHealthCheckerLoggingMW(mockLogger)(mockHealthChecker)
+		healthCheckName = "name"
+		report          = json.RawMessage(`{"status": "KO", "modules": {"redis": [{"name": "ping", "status": "KO", "error": "fail"}, {"name": "write", "status": "Degraded", "error": "slow"}]}}`)
+	)
+
+	// KO report.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), healthCheckName).Return(report, nil).Times(1)
+	gomock.InOrder(
+		mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", healthCheckName, "status", "KO", "took", gomock.Any()).Return(nil).Times(1),
+		mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", healthCheckName, "check", "redis/ping", "status", "KO", "error", "fail").Return(nil).Times(1),
+		mockLogger.EXPECT().Log(level.Key(), level.WarnValue(), "unit", "HealthCheck", "name", healthCheckName, "check", "redis/write", "status", "Degraded", "error", "slow").Return(nil).Times(1),
+	)
+	m.HealthCheck(context.Background(), healthCheckName)
+
+	// Error.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), healthCheckName).Return(nil, fmt.Errorf("fail")).Times(1)
+	mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", healthCheckName, "error", "fail", "took", gomock.Any()).Return(nil).Times(1)
+	var _, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.NotNil(t, err)
+}
+
+func TestLoggingMWCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var m = MakeHealthCheckerLoggingMW(mockLogger)(r)
+
+	// The typed API of the registry is kept.
+	mockRedis.EXPECT().Do("PING").Return(nil, fmt.Errorf("fail")).Times(1)
+	gomock.InOrder(
+		mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", "redis/ping", "status", "KO", "took", gomock.Any()).Return(nil).Times(1),
+		mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", "redis/ping", "check", "redis/ping", "status", "KO", "error", gomock.Any()).Return(nil).Times(1),
+	)
+	var results, err = m.(Checker).Check(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, KO, results[0].Status)
+
+	// Unknown check.
+	mockLogger.EXPECT().Log(level.Key(), level.ErrorValue(), "unit", "HealthCheck", "name", "unknown", "error", gomock.Any(), "took", gomock.Any()).Return(nil).Times(1)
+	_, err = m.(Checker).Check(context.Background(), "unknown")
+	assert.NotNil(t, err)
+
+	// The names of the registry are forwarded.
+	assert.Equal(t, []string{"redis", "redis/ping"}, m.(HealthCheckNamer).HealthCheckNames())
+}
+
+type correlationIDKey struct{}
+
+func TestLoggingMWCorrelationID(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		corrID = strconv.FormatUint(rand.Uint64(), 10)
+		m      = MakeHealthCheckerLoggingMW(mockLogger, WithCorrelationIDKey(correlationIDKey{}), WithCorrelationIDGenerator(func() string { return corrID }))(mockHealthChecker)
+	)
+
+	// The generated correlation ID is given to the health checks.
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(ctx context.Context, _ string) (json.RawMessage, error) {
+		assert.Equal(t, corrID, ctx.Value(correlationIDKey{}))
+		return okReport, nil
+	}).Times(1)
+	mockLogger.EXPECT().Log(level.Key(), level.InfoValue(), "unit", "HealthCheck", "correlation_id", corrID, "name", "", "status", "OK", "took", gomock.Any()).Return(nil).Times(1)
+	m.HealthCheck(context.Background(), "")
+
+	// The correlation ID of the context is used.
+	var ctx = context.WithValue(context.Background(), correlationIDKey{}, "id")
+	mockHealthChecker.EXPECT().HealthCheck(ctx, "").Return(okReport, nil).Times(1)
+	mockLogger.EXPECT().Log(level.Key(), level.InfoValue(), "unit", "HealthCheck", "correlation_id", "id", "name", "", "status", "OK", "took", gomock.Any()).Return(nil).Times(1)
+	m.HealthCheck(ctx, "")
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var logger = NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	assert.Nil(t, level.Error(logger).Log("msg", "message", "unit", "HealthCheck", "status", "KO"))
+	assert.Contains(t, buf.String(), `level=ERROR msg=message unit=HealthCheck status=KO`)
+
+	buf.Reset()
+	assert.Nil(t, level.Warn(logger).Log("unit", "HealthCheck", "odd"))
+	assert.Contains(t, buf.String(), `level=WARN msg="" unit=HealthCheck odd=(MISSING)`)
+
+	buf.Reset()
+	assert.Nil(t, logger.Log("unit", "HealthCheck"))
+	assert.Contains(t, buf.String(), `level=INFO`)
 }
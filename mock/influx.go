@@ -13,6 +13,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	client "github.com/influxdata/influxdb1-client/v2"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -55,3 +56,32 @@ func (mr *InfluxClientMockRecorder) Ping(timeout any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*InfluxClient)(nil).Ping), timeout)
 }
+
+// Query mocks base method.
+func (m *InfluxClient) Query(q client.Query) (*client.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", q)
+	ret0, _ := ret[0].(*client.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *InfluxClientMockRecorder) Query(q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*InfluxClient)(nil).Query), q)
+}
+
+// Write mocks base method.
+func (m *InfluxClient) Write(bp client.BatchPoints) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Write", bp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Write indicates an expected call of Write.
+func (mr *InfluxClientMockRecorder) Write(bp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*InfluxClient)(nil).Write), bp)
+}
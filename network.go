@@ -0,0 +1,223 @@
+package common
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NetworkTarget is a host:port target of the network health checks.
+type NetworkTarget struct {
+	// Name is the name of the target in the check results, the address by default.
+	Name    string
+	Address string
+	// TLS enables the TLS handshake check of the target.
+	TLS bool
+}
+
+// NetworkResolver is the interface of the DNS resolver, e.g. *net.Resolver.
+type NetworkResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// NetworkDialer is the interface of the TCP dialer, e.g. *net.Dialer.
+type NetworkDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NetworkOption is an option of the network module.
+type NetworkOption func(*NetworkModule)
+
+// WithResolver sets the DNS resolver. By default, net.DefaultResolver is used.
+func WithResolver(resolver NetworkResolver) NetworkOption {
+	return func(m *NetworkModule) {
+		m.resolver = resolver
+	}
+}
+
+// WithDialer sets the TCP dialer. By default, a net.Dialer is used.
+func WithDialer(dialer NetworkDialer) NetworkOption {
+	return func(m *NetworkModule) {
+		m.dialer = dialer
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the handshakes. When its ServerName is empty, the host of the
+// target is used. A nil configuration is the default one.
+func WithTLSConfig(config *tls.Config) NetworkOption {
+	return func(m *NetworkModule) {
+		if config == nil {
+			config = &tls.Config{}
+		}
+		m.tlsConfig = config
+	}
+}
+
+// WithCertExpiryWarning sets the number of days before the expiry of a certificate from which the TLS
+// check is Degraded. The default is 14 days.
+func WithCertExpiryWarning(days int) NetworkOption {
+	return func(m *NetworkModule) {
+		m.certExpiryWarning = time.Duration(days) * 24 * time.Hour
+	}
+}
+
+// NewNetworkModule returns the network health module, checking the DNS resolution, the TCP connection and
+// optionally the TLS handshake of the targets.
+func NewNetworkModule(targets []NetworkTarget, enabled bool, options ...NetworkOption) *NetworkModule {
+	var m = &NetworkModule{
+		targets:           targets,
+		enabled:           enabled,
+		resolver:          net.DefaultResolver,
+		dialer:            &net.Dialer{},
+		tlsConfig:         &tls.Config{},
+		certExpiryWarning: 14 * 24 * time.Hour,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// NetworkModule is the health check module for the network connectivity.
+type NetworkModule struct {
+	targets           []NetworkTarget
+	enabled           bool
+	resolver          NetworkResolver
+	dialer            NetworkDialer
+	tlsConfig         *tls.Config
+	certExpiryWarning time.Duration
+}
+
+// HealthCheckNames returns the names of the network health checks.
+func (m *NetworkModule) HealthCheckNames() []string {
+	return []string{"dns", "tcp", "tls"}
+}
+
+// HealthCheck executes the desired network health check.
+func (m *NetworkModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired network health check and returns its results, one per target.
+func (m *NetworkModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	if !m.enabled {
+		return deactivated("network"), nil
+	}
+
+	var checks []func(context.Context) CheckResult
+	switch name {
+	case "":
+		checks = append(append(m.checks(m.dnsCheck, false), m.checks(m.tcpCheck, false)...), m.checks(m.tlsCheck, true)...)
+	case "dns":
+		checks = m.checks(m.dnsCheck, false)
+	case "tcp":
+		checks = m.checks(m.tcpCheck, false)
+	case "tls":
+		checks = m.checks(m.tlsCheck, true)
+	default:
+		return nil, &ErrInvalidHCName{name}
+	}
+
+	return runChecks(ctx, checks...), nil
+}
+
+// checks returns the check of each target, only of the TLS targets if tlsOnly is true.
+func (m *NetworkModule) checks(check func(NetworkTarget) func(context.Context) CheckResult, tlsOnly bool) []func(context.Context) CheckResult {
+	var checks []func(context.Context) CheckResult
+	for _, t := range m.targets {
+		if t.Name == "" {
+			t.Name = t.Address
+		}
+		if !tlsOnly || t.TLS {
+			checks = append(checks, check(t))
+		}
+	}
+	return checks
+}
+
+func (m *NetworkModule) dnsCheck(t NetworkTarget) func(context.Context) CheckResult {
+	return func(ctx context.Context) CheckResult {
+		return runDetailedCheck(ctx, "dns "+t.Name, func(ctx context.Context) (map[string]interface{}, error) {
+			var host, _, err = net.SplitHostPort(t.Address)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid address %s", t.Address)
+			}
+
+			var addrs []string
+			addrs, err = m.resolver.LookupHost(ctx, host)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not resolve %s", host)
+			}
+			if len(addrs) == 0 {
+				return nil, errors.Errorf("%s resolved to no address", host)
+			}
+			return map[string]interface{}{"addresses": addrs}, nil
+		})
+	}
+}
+
+func (m *NetworkModule) tcpCheck(t NetworkTarget) func(context.Context) CheckResult {
+	return func(ctx context.Context) CheckResult {
+		return runCheck(ctx, "tcp "+t.Name, func(ctx context.Context) error {
+			var conn, err = m.dialer.DialContext(ctx, "tcp", t.Address)
+			if err != nil {
+				return errors.Wrapf(err, "could not connect to %s", t.Address)
+			}
+			return conn.Close()
+		})
+	}
+}
+
+func (m *NetworkModule) tlsCheck(t NetworkTarget) func(context.Context) CheckResult {
+	return func(ctx context.Context) CheckResult {
+		return runDetailedCheck(ctx, "tls "+t.Name, func(ctx context.Context) (map[string]interface{}, error) {
+			var host, _, err = net.SplitHostPort(t.Address)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid address %s", t.Address)
+			}
+
+			var conn net.Conn
+			conn, err = m.dialer.DialContext(ctx, "tcp", t.Address)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not connect to %s", t.Address)
+			}
+			defer conn.Close()
+
+			var config = m.tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+
+			var tlsConn = tls.Client(conn, config)
+			if err = tlsConn.HandshakeContext(ctx); err != nil {
+				return nil, errors.Wrapf(err, "tls handshake with %s failed", t.Address)
+			}
+
+			var certs = tlsConn.ConnectionState().PeerCertificates
+			if len(certs) == 0 {
+				return nil, errors.Errorf("%s presented no certificate", t.Address)
+			}
+
+			var cert = certs[0]
+			var left = time.Until(cert.NotAfter)
+			var details = map[string]interface{}{
+				"subject":   cert.Subject.String(),
+				"issuer":    cert.Issuer.String(),
+				"not_after": cert.NotAfter.UTC().Format(time.RFC3339),
+				"days_left": int(left.Hours() / 24),
+			}
+
+			switch {
+			case left <= 0:
+				return details, errors.Errorf("certificate of %s expired on %s", t.Address, details["not_after"])
+			case left < m.certExpiryWarning:
+				return details, degraded(errors.Errorf("certificate of %s expires in %d days", t.Address, details["days_left"]))
+			}
+			return details, nil
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package common_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	var addrs, ok = r[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host")
+	}
+	return addrs, nil
+}
+
+func TestNetworkModule(t *testing.T) {
+	var tlsServer = httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	var listener, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	var pool = x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+
+	var (
+		resolver = fakeResolver{"127.0.0.1": {"127.0.0.1"}, "localhost": {"127.0.0.1"}}
+		targets  = []NetworkTarget{
+			{Name: "tcp", Address: listener.Addr().String()},
+			{Address: tlsServer.Listener.Addr().String(), TLS: true},
+		}
+		m = NewNetworkModule(targets, true, WithResolver(resolver), WithTLSConfig(&tls.Config{RootCAs: pool}))
+	)
+
+	assert.Equal(t, []string{"dns", "tcp", "tls"}, m.HealthCheckNames())
+
+	var results []CheckResult
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 5)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+		assert.Equal(t, OK, r.Status, r.Error)
+	}
+	var tlsName = tlsServer.Listener.Addr().String()
+	assert.Equal(t, []string{"dns tcp", "dns " + tlsName, "tcp tcp", "tcp " + tlsName, "tls " + tlsName}, names)
+	assert.Equal(t, []string{"127.0.0.1"}, results[0].Details["addresses"])
+	assert.Contains(t, results[4].Details, "not_after")
+
+	results, err = m.Check(context.Background(), "tls")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	// Unknown check.
+	_, err = m.Check(context.Background(), "unknown")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+
+	// Disabled.
+	m = NewNetworkModule(targets, false)
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, Deactivated, results[0].Status)
+}
+
+func TestNetworkModuleFailures(t *testing.T) {
+	var tlsServer = httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	// A closed port.
+	var listener, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	var closed = listener.Addr().String()
+	listener.Close()
+
+	var pool = x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+
+	var targets = []NetworkTarget{
+		{Name: "unknown", Address: "unknown.invalid:80"},
+		{Name: "closed", Address: closed, TLS: true},
+		{Name: "server", Address: tlsServer.Listener.Addr().String(), TLS: true},
+	}
+
+	// The certificate of the server is not trusted.
+	var m = NewNetworkModule(targets, true, WithResolver(fakeResolver{"127.0.0.1": {"127.0.0.1"}}))
+	var results []CheckResult
+	results, err = m.Check(context.Background(), "dns")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Contains(t, results[0].Error, "could not resolve unknown.invalid")
+	assert.Equal(t, OK, results[1].Status)
+
+	results, err = m.Check(context.Background(), "tls")
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Contains(t, results[0].Error, "could not connect")
+	assert.Equal(t, KO, results[1].Status)
+	assert.Contains(t, results[1].Error, "tls handshake")
+
+	// A nil TLS configuration is the default one.
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m = NewNetworkModule(targets, true, WithTLSConfig(nil))
+	results, err = m.Check(ctx, "tls")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[1].Status)
+	assert.Contains(t, results[1].Error, "tls handshake")
+
+	// The certificate expires soon.
+	m = NewNetworkModule(targets, true, WithTLSConfig(&tls.Config{RootCAs: pool}), WithCertExpiryWarning(100*365))
+	results, err = m.Check(context.Background(), "tls")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[1].Status)
+	assert.Contains(t, results[1].Error, "expires in")
+	assert.True(t, results[1].Details["days_left"].(int) > 0)
+
+	// Timeout.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	m = NewNetworkModule([]NetworkTarget{{Address: "127.0.0.1:80"}}, true, WithDialer(blockingDialer{}))
+	results, err = m.Check(ctx, "tcp")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, ErrTimeout.Error(), results[0].Error)
+}
+
+// blockingDialer is a dialer that ignores the context and never connects.
+type blockingDialer struct{}
+
+func (blockingDialer) DialContext(context.Context, string, string) (net.Conn, error) {
+	time.Sleep(time.Second)
+	return nil, fmt.Errorf("fail")
+}
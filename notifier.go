@@ -0,0 +1,264 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// StatusChanged is the event emitted when the status of a check changes.
+type StatusChanged struct {
+	Module    string    `json:"module,omitempty"`
+	Name      string    `json:"name"`
+	Old       Status    `json:"old"`
+	New       Status    `json:"new"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StatusListener is notified of the status changes.
+type StatusListener interface {
+	StatusChanged(ctx context.Context, event StatusChanged) error
+}
+
+// StatusListenerFunc is an adapter to use a function as a StatusListener.
+type StatusListenerFunc func(ctx context.Context, event StatusChanged) error
+
+// StatusChanged implements StatusListener.
+func (f StatusListenerFunc) StatusChanged(ctx context.Context, event StatusChanged) error {
+	return f(ctx, event)
+}
+
+// notifierQueueSize is the number of status changes that can wait to be notified.
+const notifierQueueSize = 100
+
+// NewNotifier returns a health checker that tracks the previous status of each check of next, and notifies
+// the listeners when it changes. The first status of a check is not notified, as there is nothing to
+// compare it with. The listeners are called in a background goroutine, once started, so that slow listeners
+// do not delay the health checks. The listener errors are logged, as are the status changes dropped when
+// too many are waiting to be notified.
+func NewNotifier(next HealthChecker, logger log.Logger) *Notifier {
+	return &Notifier{
+		next:     next,
+		logger:   logger,
+		statuses: map[string]Status{},
+		events:   make(chan StatusChanged, notifierQueueSize),
+	}
+}
+
+// Notifier is a HealthChecker that notifies the status changes of the checks to its listeners.
+type Notifier struct {
+	next   HealthChecker
+	logger log.Logger
+	events chan StatusChanged
+
+	mutex     sync.Mutex
+	statuses  map[string]Status
+	listeners []StatusListener
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// AddListener registers a listener of the status changes.
+func (n *Notifier) AddListener(l StatusListener) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.listeners = append(n.listeners, l)
+}
+
+// Start starts notifying the status changes in the background. The changes that occurred before are
+// notified first. Starting a started notifier has no effect.
+func (n *Notifier) Start() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.cancel != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, n.cancel = context.WithCancel(context.Background())
+	n.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-n.events:
+				if ctx.Err() != nil {
+					return
+				}
+				n.deliver(ctx, e)
+			}
+		}
+	}(n.done)
+}
+
+// Stop stops notifying the status changes, cancels the running notification and waits for it to return.
+// The status changes still queued are notified if the notifier is started again.
+func (n *Notifier) Stop() {
+	n.mutex.Lock()
+	var cancel, done = n.cancel, n.done
+	n.cancel, n.done = nil, nil
+	n.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// HealthCheck implements HealthChecker.
+func (n *Notifier) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var report, err = n.next.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if results, err := decodeReport(report); err == nil {
+		n.notify(results)
+	}
+	return report, nil
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (n *Notifier) HealthCheckNames() []string {
+	return healthCheckNames(n.next)
+}
+
+// Check implements Checker.
+func (n *Notifier) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var results, err = check(ctx, n.next, name)
+	if err != nil {
+		return nil, err
+	}
+
+	n.notify(results)
+	return results, nil
+}
+
+// notify queues the status changes, without waiting for the listeners.
+func (n *Notifier) notify(results []CheckResult) {
+	for _, e := range n.update(results) {
+		select {
+		case n.events <- e:
+		default:
+			level.Error(n.logger).Log("unit", "Notifier", "module", e.Module, "name", e.Name, "error", "too many status changes waiting, change dropped")
+		}
+	}
+}
+
+// deliver notifies the status change to all the listeners.
+func (n *Notifier) deliver(ctx context.Context, e StatusChanged) {
+	n.mutex.Lock()
+	var listeners = n.listeners
+	n.mutex.Unlock()
+
+	for _, l := range listeners {
+		if err := l.StatusChanged(ctx, e); err != nil {
+			level.Error(n.logger).Log("unit", "Notifier", "module", e.Module, "name", e.Name, "error", err.Error())
+		}
+	}
+}
+
+// update records the statuses of the results and returns the changes.
+func (n *Notifier) update(results []CheckResult) []StatusChanged {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var events []StatusChanged
+	for _, r := range results {
+		var key = r.Module + "/" + r.Name
+		var old, ok = n.statuses[key]
+		n.statuses[key] = r.Status
+
+		if ok && old != r.Status {
+			var timestamp = r.End
+			if timestamp.IsZero() {
+				timestamp = time.Now()
+			}
+			events = append(events, StatusChanged{
+				Module:    r.Module,
+				Name:      r.Name,
+				Old:       old,
+				New:       r.Status,
+				Error:     r.Error,
+				Timestamp: timestamp,
+			})
+		}
+	}
+	return events
+}
+
+// NewWebhookListener returns a listener that POSTs the status changes as JSON to url. A failed request, i.e.
+// one that returns an error or a status code other than 2xx, is retried up to retries times, waiting
+// backoff before the first retry and doubling it after each one.
+func NewWebhookListener(client HTTPRequestClient, url string, retries int, backoff time.Duration) *WebhookListener {
+	return &WebhookListener{
+		client:  client,
+		url:     url,
+		retries: retries,
+		backoff: backoff,
+	}
+}
+
+// WebhookListener is a StatusListener that notifies a webhook.
+type WebhookListener struct {
+	client  HTTPRequestClient
+	url     string
+	retries int
+	backoff time.Duration
+}
+
+// StatusChanged implements StatusListener.
+func (l *WebhookListener) StatusChanged(ctx context.Context, event StatusChanged) error {
+	var body, err = json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal event")
+	}
+
+	var backoff = l.backoff
+	for attempt := 0; ; attempt++ {
+		err = l.post(ctx, body)
+		if err == nil || attempt >= l.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return contextError(ctx)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (l *WebhookListener) post(ctx context.Context, body []byte) error {
+	var req, err = http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var res *http.Response
+	res, err = l.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "could not post to %s", l.url)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("webhook %s returned status code %d", l.url, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// receive waits for the next status change notified on the channel.
+func receive(t *testing.T, events chan StatusChanged) StatusChanged {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		assert.Fail(t, "status change not notified")
+		return StatusChanged{}
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var n = NewNotifier(r, log.NewNopLogger())
+	n.Start()
+	defer n.Stop()
+
+	var events = make(chan StatusChanged, 10)
+	n.AddListener(StatusListenerFunc(func(_ context.Context, e StatusChanged) error {
+		events <- e
+		return nil
+	}))
+
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(2),
+		mockRedis.EXPECT().Do("PING").Return(nil, fmt.Errorf("fail")).Times(2),
+		mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(1),
+	)
+
+	// The first status is not notified.
+	var _, err = n.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	// Same status.
+	_, err = n.HealthCheck(context.Background(), "redis")
+	assert.Nil(t, err)
+
+	// OK to KO.
+	_, err = n.HealthCheck(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+	var e = receive(t, events)
+	assert.Equal(t, "redis", e.Module)
+	assert.Equal(t, "ping", e.Name)
+	assert.Equal(t, OK, e.Old)
+	assert.Equal(t, KO, e.New)
+	assert.Equal(t, "could not ping redis: fail", e.Error)
+	assert.False(t, e.Timestamp.IsZero())
+
+	// KO to OK, with the typed API.
+	_, err = n.Check(context.Background(), "")
+	assert.Nil(t, err)
+	_, err = n.Check(context.Background(), "")
+	assert.Nil(t, err)
+	e = receive(t, events)
+	assert.Equal(t, KO, e.Old)
+	assert.Equal(t, OK, e.New)
+	assert.Equal(t, "", e.Error)
+	assert.Empty(t, events)
+}
+
+func TestNotifierErrors(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var n = NewNotifier(mockHealthChecker, mockLogger)
+	n.Start()
+	defer n.Stop()
+
+	var notified = make(chan struct{}, 10)
+	n.AddListener(StatusListenerFunc(func(context.Context, StatusChanged) error {
+		return fmt.Errorf("fail")
+	}))
+	n.AddListener(StatusListenerFunc(func(context.Context, StatusChanged) error {
+		notified <- struct{}{}
+		return nil
+	}))
+
+	// The health check errors are returned.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(nil, fmt.Errorf("fail")).Times(1)
+	var _, err = n.HealthCheck(context.Background(), "")
+	assert.NotNil(t, err)
+
+	// The listener errors are logged, and the other listeners are notified.
+	gomock.InOrder(
+		mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1),
+		mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(koReport, nil).Times(1),
+	)
+	mockLogger.EXPECT().Log("level", gomock.Any(), "unit", "Notifier", "module", "", "name", "ping", "error", "fail").Return(nil).Times(1)
+	_, err = n.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+	_, err = n.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	select {
+	case <-notified:
+	case <-time.After(time.Second):
+		assert.Fail(t, "status change not notified")
+	}
+}
+
+func TestNotifierAsynchronous(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	// The status changes at every health check.
+	var calls int
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		calls++
+		if calls%2 == 0 {
+			return koReport, nil
+		}
+		return okReport, nil
+	}).AnyTimes()
+
+	var n = NewNotifier(mockHealthChecker, mockLogger)
+
+	// A blocked listener does not delay the health checks, and is canceled when the notifier stops.
+	var (
+		started  = make(chan struct{})
+		canceled = make(chan error, 1)
+	)
+	n.AddListener(StatusListenerFunc(func(ctx context.Context, _ StatusChanged) error {
+		close(started)
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return nil
+	}))
+
+	// The status changes are queued until the notifier is started, the changes that do not fit are dropped.
+	mockLogger.EXPECT().Log("level", gomock.Any(), "unit", "Notifier", "module", "", "name", "ping", "error", "too many status changes waiting, change dropped").Return(nil).Times(1)
+	for i := 0; i < 102; i++ {
+		var _, err = n.HealthCheck(context.Background(), "")
+		assert.Nil(t, err)
+	}
+
+	n.Start()
+	n.Start()
+	<-started
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var _, err = n.HealthCheck(ctx, "")
+	assert.Nil(t, err)
+	assert.Nil(t, ctx.Err())
+
+	n.Stop()
+	assert.Equal(t, context.Canceled, <-canceled)
+}
+
+func TestNotifierRestart(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var n = NewNotifier(r, log.NewNopLogger())
+
+	var events = make(chan StatusChanged, 10)
+	n.AddListener(StatusListenerFunc(func(_ context.Context, e StatusChanged) error {
+		events <- e
+		return nil
+	}))
+
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(1),
+		mockRedis.EXPECT().Do("PING").Return(nil, fmt.Errorf("fail")).Times(1),
+	)
+
+	n.Start()
+	n.Stop()
+
+	// The changes that occurred while the notifier was stopped are notified when it is started again.
+	var _, err = n.Check(context.Background(), "")
+	assert.Nil(t, err)
+	_, err = n.Check(context.Background(), "")
+	assert.Nil(t, err)
+
+	n.Start()
+	defer n.Stop()
+	var e = receive(t, events)
+	assert.Equal(t, OK, e.Old)
+	assert.Equal(t, KO, e.New)
+}
+
+func TestWebhookListener(t *testing.T) {
+	var (
+		received = make(chan StatusChanged, 10)
+		attempts int32
+	)
+	var s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		// The first attempt fails.
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var body, _ = io.ReadAll(r.Body)
+		var e StatusChanged
+		assert.Nil(t, json.Unmarshal(body, &e))
+		assert.Contains(t, string(body), `"old":"OK","new":"KO"`)
+		received <- e
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	var (
+		l     = NewWebhookListener(s.Client(), s.URL, 2, time.Millisecond)
+		event = StatusChanged{Module: "redis", Name: "ping", Old: OK, New: KO, Error: "fail", Timestamp: time.Now().UTC()}
+	)
+
+	assert.Nil(t, l.StatusChanged(context.Background(), event))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	var e = <-received
+	assert.Equal(t, event.Module, e.Module)
+	assert.Equal(t, event.Name, e.Name)
+	assert.Equal(t, event.Old, e.Old)
+	assert.Equal(t, event.New, e.New)
+	assert.Equal(t, event.Error, e.Error)
+	assert.True(t, event.Timestamp.Equal(e.Timestamp))
+}
+
+func TestWebhookListenerFailure(t *testing.T) {
+	var attempts int32
+	var s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	var event = StatusChanged{Name: "ping", Old: OK, New: KO}
+
+	// The retries are exhausted.
+	var l = NewWebhookListener(s.Client(), s.URL, 2, time.Millisecond)
+	var err = l.StatusChanged(context.Background(), event)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "502")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// The retries stop when the context is canceled.
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	l = NewWebhookListener(s.Client(), s.URL, 5, time.Hour)
+	err = l.StatusChanged(ctx, event)
+	assert.Equal(t, ErrCanceled, err)
+
+	// Unreachable webhook.
+	s.Close()
+	l = NewWebhookListener(s.Client(), s.URL, 0, time.Millisecond)
+	err = l.StatusChanged(context.Background(), event)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"sync"
+)
+
+type limiterKey struct{}
+
+// limiter bounds the number of goroutines executing health checks. The goroutine that starts the health
+// checks executes them too, so the limiter holds the slots of the additional goroutines.
+type limiter chan struct{}
+
+// WithMaxParallelism returns a copy of the context in which up to n checks are executed concurrently when
+// all the checks of a module are requested. By default, the checks are executed sequentially. The limit is
+// shared by all the modules checked with the context, e.g. by a Registry.
+func WithMaxParallelism(ctx context.Context, n int) context.Context {
+	if n < 2 {
+		return context.WithValue(ctx, limiterKey{}, limiter(nil))
+	}
+	return context.WithValue(ctx, limiterKey{}, make(limiter, n-1))
+}
+
+// maxParallelism returns the maximum number of checks executed concurrently, as set by WithMaxParallelism.
+func maxParallelism(ctx context.Context) int {
+	var l, _ = ctx.Value(limiterKey{}).(limiter)
+	return cap(l) + 1
+}
+
+// runChecks executes the checks, concurrently if allowed by the context, and returns their results in
+// the order of the checks.
+func runChecks(ctx context.Context, checks ...func(context.Context) CheckResult) []CheckResult {
+	var results = make([]CheckResult, len(checks))
+	parallel(ctx, len(checks), func(i int) {
+		results[i] = checks[i](ctx)
+	})
+	return results
+}
+
+// parallel calls f for each index in [0, n), and returns when all calls returned. A call is executed in a
+// new goroutine when the limiter of the context has a free slot, and in the calling goroutine otherwise, so
+// that nested calls never wait for each other and the number of goroutines calling f never exceeds the
+// limit. The last call is always executed in the calling goroutine, which would wait otherwise.
+func parallel(ctx context.Context, n int, f func(i int)) {
+	var l, _ = ctx.Value(limiterKey{}).(limiter)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if i == n-1 {
+			f(i)
+			break
+		}
+
+		select {
+		case l <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-l
+					wg.Done()
+				}()
+				f(i)
+			}(i)
+		default:
+			f(i)
+		}
+	}
+	wg.Wait()
+}
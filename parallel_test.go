@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// maxRunning returns a function that records the maximum number of concurrent calls to it.
+func maxRunning() (func(), *int32) {
+	var running, max int32
+	return func() {
+		var r = atomic.AddInt32(&running, 1)
+		for {
+			var m = atomic.LoadInt32(&max)
+			if r <= m || atomic.CompareAndSwapInt32(&max, m, r) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}, &max
+}
+
+func TestParallel(t *testing.T) {
+	var tsts = []struct {
+		n, max, expectedMax int32
+	}{
+		{10, 0, 1},
+		{10, 1, 1},
+		{10, 3, 3},
+		{2, 5, 2},
+		{0, 5, 0},
+	}
+
+	for _, tst := range tsts {
+		var run, max = maxRunning()
+		var calls int32
+		parallel(WithMaxParallelism(context.Background(), int(tst.max)), int(tst.n), func(i int) {
+			run()
+			atomic.AddInt32(&calls, 1)
+		})
+
+		assert.Equal(t, tst.n, calls)
+		assert.Equal(t, tst.expectedMax, *max)
+	}
+
+	// Sequential without limit.
+	var run, max = maxRunning()
+	parallel(context.Background(), 3, func(int) { run() })
+	assert.Equal(t, int32(1), *max)
+
+	// The limit is shared by the nested calls.
+	for _, limit := range []int{1, 2, 5} {
+		var ctx = WithMaxParallelism(context.Background(), limit)
+		var run, max = maxRunning()
+		var calls int32
+		parallel(ctx, 4, func(int) {
+			parallel(ctx, 3, func(int) {
+				run()
+				atomic.AddInt32(&calls, 1)
+			})
+		})
+		assert.Equal(t, int32(12), calls)
+		assert.Equal(t, int32(limit), *max)
+	}
+}
+
+func TestRunChecks(t *testing.T) {
+	var check = func(name string, delay time.Duration) func(context.Context) CheckResult {
+		return func(context.Context) CheckResult {
+			time.Sleep(delay)
+			return CheckResult{Name: name}
+		}
+	}
+	var checks = []func(context.Context) CheckResult{
+		check("slow", 40*time.Millisecond),
+		check("medium", 20*time.Millisecond),
+		check("fast", 0),
+	}
+
+	for _, ctx := range []context.Context{context.Background(), WithMaxParallelism(context.Background(), 3)} {
+		var results = runChecks(ctx, checks...)
+
+		// The order of the results is the order of the checks.
+		assert.Len(t, results, 3)
+		assert.Equal(t, "slow", results[0].Name)
+		assert.Equal(t, "medium", results[1].Name)
+		assert.Equal(t, "fast", results[2].Name)
+	}
+
+	assert.Equal(t, 1, maxParallelism(context.Background()))
+	assert.Equal(t, 1, maxParallelism(WithMaxParallelism(context.Background(), 0)))
+	assert.Equal(t, 4, maxParallelism(WithMaxParallelism(context.Background(), 4)))
+}
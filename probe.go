@@ -0,0 +1,86 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Probe is a kind of Kubernetes probe. The probes can be combined, e.g. ReadinessProbe|StartupProbe.
+type Probe int
+
+const (
+	// LivenessProbe tells whether the service must be restarted. It should only depend on the modules whose
+	// failure cannot be fixed without a restart.
+	LivenessProbe Probe = 1 << iota
+	// ReadinessProbe tells whether the service can receive traffic, e.g. it fails when the database is down.
+	ReadinessProbe
+	// StartupProbe tells whether the service started. Once successful, it stays successful.
+	StartupProbe
+)
+
+// Liveness executes the checks of the modules participating in the liveness probe.
+func (r *Registry) Liveness(ctx context.Context) (json.RawMessage, error) {
+	return r.probeReport(r.Probe(ctx, LivenessProbe))
+}
+
+// Readiness executes the checks of the modules participating in the readiness probe.
+func (r *Registry) Readiness(ctx context.Context) (json.RawMessage, error) {
+	return r.probeReport(r.Probe(ctx, ReadinessProbe))
+}
+
+// Startup executes the checks of the modules participating in the startup probe, until they all succeed
+// once. From then on, the successful results are returned without executing the checks again.
+func (r *Registry) Startup(ctx context.Context) (json.RawMessage, error) {
+	return r.probeReport(r.Probe(ctx, StartupProbe))
+}
+
+// Probe executes the checks of the modules participating in the probe and returns their results.
+func (r *Registry) Probe(ctx context.Context, probe Probe) ([]CheckResult, error) {
+	if probe == StartupProbe {
+		r.startupMutex.Lock()
+		defer r.startupMutex.Unlock()
+
+		if r.startupResults != nil {
+			return r.startupResults, nil
+		}
+	}
+
+	ctx, cancel := r.checkContext(ctx)
+	defer cancel()
+
+	var names []string
+	for _, name := range r.names {
+		if r.probes[name]&probe != 0 {
+			names = append(names, name)
+		}
+	}
+
+	var results = r.checkModules(ctx, names)
+	if probe == StartupProbe && AggregateStatus(results) != KO {
+		r.startupResults = append([]CheckResult{}, results...)
+	}
+	return results, nil
+}
+
+func (r *Registry) probeReport(results []CheckResult, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(NewReport(results), "", "  ")
+}
+
+// MakeProbeHandler makes a HTTP handler for the probe, e.g. to serve the readiness probe on /readyz. The
+// handler replies 200 when the probe succeeds and 503 otherwise.
+func MakeProbeHandler(r *Registry, probe Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var ctx = req.Context()
+
+		var report, err = r.probeReport(r.Probe(ctx, probe))
+		if err != nil {
+			encodeHealthCheckError(ctx, err, w)
+			return
+		}
+		encodeHealthCheckResponse(ctx, w, report)
+	})
+}
@@ -0,0 +1,114 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProbes(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewHealthChecker(mockCtrl)
+	var mockSentry = mock.NewHealthChecker(mockCtrl)
+	var mockFlaki = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("cockroach", mockCockroach, ReadinessProbe, StartupProbe)
+	r.Register("sentry", mockSentry, ReadinessProbe)
+	r.Register("flaki", mockFlaki)
+
+	// Sentry is down: the service is alive but not ready.
+	mockFlaki.EXPECT().HealthCheck(gomock.Any(), "").Return(okReport, nil).Times(2)
+	mockCockroach.EXPECT().HealthCheck(gomock.Any(), "").Return(okReport, nil).Times(1)
+	mockSentry.EXPECT().HealthCheck(gomock.Any(), "").Return(koReport, nil).Times(1)
+
+	var results, err = r.Probe(context.Background(), LivenessProbe)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "flaki", results[0].Module)
+	assert.Equal(t, OK, AggregateStatus(results))
+
+	var jsonReport json.RawMessage
+	jsonReport, err = r.Readiness(context.Background())
+	assert.Nil(t, err)
+
+	var report Report
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, KO, report.Status)
+	assert.Len(t, report.Modules, 3)
+	assert.Equal(t, KO, report.Modules["sentry"][0].Status)
+}
+
+func TestStartupProbe(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewHealthChecker(mockCtrl)
+	var mockSentry = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("cockroach", mockCockroach, StartupProbe|ReadinessProbe)
+	r.Register("sentry", mockSentry, ReadinessProbe)
+
+	// Not started yet.
+	mockCockroach.EXPECT().HealthCheck(gomock.Any(), "").Return(koReport, nil).Times(1)
+	var jsonReport, err = r.Startup(context.Background())
+	assert.Nil(t, err)
+
+	var report Report
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, KO, report.Status)
+	assert.Len(t, report.Modules, 1)
+
+	// Started.
+	mockCockroach.EXPECT().HealthCheck(gomock.Any(), "").Return(okReport, nil).Times(1)
+	var results []CheckResult
+	results, err = r.Probe(context.Background(), StartupProbe)
+	assert.Nil(t, err)
+	assert.Equal(t, OK, AggregateStatus(results))
+
+	// Latched: the checks are not executed anymore.
+	for i := 0; i < 3; i++ {
+		results, err = r.Probe(context.Background(), StartupProbe)
+		assert.Nil(t, err)
+		assert.Equal(t, OK, AggregateStatus(results))
+		assert.Equal(t, "cockroach", results[0].Module)
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewHealthChecker(mockCtrl)
+	var mockSentry = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("cockroach", mockCockroach, ReadinessProbe)
+	r.Register("sentry", mockSentry, ReadinessProbe)
+
+	var mux = http.NewServeMux()
+	mux.Handle("/livez", MakeProbeHandler(r, LivenessProbe))
+	mux.Handle("/readyz", MakeProbeHandler(r, ReadinessProbe))
+	var s = httptest.NewServer(mux)
+	defer s.Close()
+
+	// No module participates in the liveness probe.
+	var res, err = http.Get(s.URL + "/livez")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	mockCockroach.EXPECT().HealthCheck(gomock.Any(), "").Return(okReport, nil).Times(1)
+	mockSentry.EXPECT().HealthCheck(gomock.Any(), "").Return(koReport, nil).Times(1)
+	res, err = http.Get(s.URL + "/readyz")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	res.Body.Close()
+}
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets are the default buckets of the healthcheck_duration_seconds histogram, in seconds.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewPrometheusCollector returns a collector exposing the health check results in the Prometheus text
+// format, with the given histogram buckets, or DefaultDurationBuckets if there are none.
+func NewPrometheusCollector(buckets ...float64) *PrometheusCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusCollector{
+		buckets: buckets,
+		series:  map[seriesKey]*checkSeries{},
+	}
+}
+
+// PrometheusCollector collects health check results and exposes, per module and check:
+//   - healthcheck_status, a gauge set to 1 for the current status and 0 for the others,
+//   - healthcheck_duration_seconds, a histogram of the durations of the checks that are not Deactivated,
+//   - healthcheck_failures_total, a counter of the KO results.
+type PrometheusCollector struct {
+	buckets []float64
+
+	mutex  sync.Mutex
+	series map[seriesKey]*checkSeries
+}
+
+type seriesKey struct {
+	module string
+	check  string
+}
+
+type checkSeries struct {
+	status       Status
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+	failures     uint64
+}
+
+// Observe records the results of the checks of the module. When the results come from a Registry, module
+// can be empty: the Module of each result is used.
+func (c *PrometheusCollector) Observe(module string, results []CheckResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, r := range results {
+		var key = seriesKey{module: module, check: r.Name}
+		if r.Module != "" {
+			key.module = r.Module
+		}
+
+		var s, ok = c.series[key]
+		if !ok {
+			s = &checkSeries{bucketCounts: make([]uint64, len(c.buckets))}
+			c.series[key] = s
+		}
+
+		s.status = r.Status
+		if r.Status == KO {
+			s.failures++
+		}
+		if r.Status != Deactivated {
+			var seconds = r.Duration.Seconds()
+			for i, b := range c.buckets {
+				if seconds <= b {
+					s.bucketCounts[i]++
+				}
+			}
+			s.sum += seconds
+			s.count++
+		}
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format. It implements io.WriterTo.
+func (c *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	c.mutex.Lock()
+	var keys = make([]seriesKey, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].module != keys[j].module {
+			return keys[i].module < keys[j].module
+		}
+		return keys[i].check < keys[j].check
+	})
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# HELP healthcheck_status Current status of the health check, 1 for the current status and 0 for the others.\n")
+	buf.WriteString("# TYPE healthcheck_status gauge\n")
+	for _, k := range keys {
+		for st := Status(0); int(st) < len(_Status_index)-1; st++ {
+			var v = 0
+			if c.series[k].status == st {
+				v = 1
+			}
+			fmt.Fprintf(&buf, "healthcheck_status{%s,status=%q} %d\n", k.labels(), st.String(), v)
+		}
+	}
+
+	buf.WriteString("# HELP healthcheck_duration_seconds Duration of the health check.\n")
+	buf.WriteString("# TYPE healthcheck_duration_seconds histogram\n")
+	for _, k := range keys {
+		var s = c.series[k]
+		for i, b := range c.buckets {
+			fmt.Fprintf(&buf, "healthcheck_duration_seconds_bucket{%s,le=\"%s\"} %d\n", k.labels(), formatFloat(b), s.bucketCounts[i])
+		}
+		fmt.Fprintf(&buf, "healthcheck_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", k.labels(), s.count)
+		fmt.Fprintf(&buf, "healthcheck_duration_seconds_sum{%s} %s\n", k.labels(), formatFloat(s.sum))
+		fmt.Fprintf(&buf, "healthcheck_duration_seconds_count{%s} %d\n", k.labels(), s.count)
+	}
+
+	buf.WriteString("# HELP healthcheck_failures_total Number of failed health checks.\n")
+	buf.WriteString("# TYPE healthcheck_failures_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "healthcheck_failures_total{%s} %d\n", k.labels(), c.series[k].failures)
+	}
+	c.mutex.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics, so that the collector can be scraped directly.
+func (c *PrometheusCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+func (k seriesKey) labels() string {
+	return fmt.Sprintf("module=\"%s\",check=\"%s\"", escapeLabelValue(k.module), escapeLabelValue(k.check))
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,96 @@
+package common_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	var c = NewPrometheusCollector(1, 0.1)
+
+	c.Observe("redis", []CheckResult{{Name: "ping", Status: OK, Duration: 50 * time.Millisecond}})
+	c.Observe("redis", []CheckResult{{Name: "ping", Status: KO, Duration: 2 * time.Second}})
+	c.Observe("", []CheckResult{{Module: "influx", Name: "influx", Status: Deactivated}})
+
+	var buf bytes.Buffer
+	var _, err = c.WriteTo(&buf)
+	assert.Nil(t, err)
+
+	var expected = `# HELP healthcheck_status Current status of the health check, 1 for the current status and 0 for the others.
+# TYPE healthcheck_status gauge
+healthcheck_status{module="influx",check="influx",status="OK"} 0
+healthcheck_status{module="influx",check="influx",status="KO"} 0
+healthcheck_status{module="influx",check="influx",status="Deactivated"} 1
+healthcheck_status{module="influx",check="influx",status="Degraded"} 0
+healthcheck_status{module="redis",check="ping",status="OK"} 0
+healthcheck_status{module="redis",check="ping",status="KO"} 1
+healthcheck_status{module="redis",check="ping",status="Deactivated"} 0
+healthcheck_status{module="redis",check="ping",status="Degraded"} 0
+# HELP healthcheck_duration_seconds Duration of the health check.
+# TYPE healthcheck_duration_seconds histogram
+healthcheck_duration_seconds_bucket{module="influx",check="influx",le="0.1"} 0
+healthcheck_duration_seconds_bucket{module="influx",check="influx",le="1"} 0
+healthcheck_duration_seconds_bucket{module="influx",check="influx",le="+Inf"} 0
+healthcheck_duration_seconds_sum{module="influx",check="influx"} 0
+healthcheck_duration_seconds_count{module="influx",check="influx"} 0
+healthcheck_duration_seconds_bucket{module="redis",check="ping",le="0.1"} 1
+healthcheck_duration_seconds_bucket{module="redis",check="ping",le="1"} 1
+healthcheck_duration_seconds_bucket{module="redis",check="ping",le="+Inf"} 2
+healthcheck_duration_seconds_sum{module="redis",check="ping"} 2.05
+healthcheck_duration_seconds_count{module="redis",check="ping"} 2
+# HELP healthcheck_failures_total Number of failed health checks.
+# TYPE healthcheck_failures_total counter
+healthcheck_failures_total{module="influx",check="influx"} 0
+healthcheck_failures_total{module="redis",check="ping"} 1
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPrometheusCollectorLabelEscaping(t *testing.T) {
+	var c = NewPrometheusCollector()
+	c.Observe("my\"module", []CheckResult{{Name: "a\\b\nc", Status: OK}})
+
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	assert.Contains(t, buf.String(), `healthcheck_failures_total{module="my\"module",check="a\\b\nc"} 0`)
+}
+
+func TestPrometheusCollectorWithModules(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var (
+		c = NewPrometheusCollector()
+		r = NewRegistry()
+	)
+	r.Register("redis", NewRedisModule(mockRedis, true))
+
+	mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(1)
+	var results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	c.Observe("", results)
+
+	var s = httptest.NewServer(c)
+	defer s.Close()
+
+	var res *http.Response
+	res, err = http.Get(s.URL)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+
+	var body, _ = io.ReadAll(res.Body)
+	assert.Contains(t, string(body), `healthcheck_status{module="redis",check="ping",status="OK"} 1`)
+	assert.Contains(t, string(body), `healthcheck_duration_seconds_count{module="redis",check="ping"} 1`)
+}
@@ -4,23 +4,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
-// NewRedisModule returns the redis health module.
-func NewRedisModule(redis RedisClient, enabled bool) *RedisModule {
-	return &RedisModule{
+// RedisOption is an option of the redis module.
+type RedisOption func(*RedisModule)
+
+// WithRedisWriteCheck enables the "write" check, that sets a unique key expiring after ttl, reads it back
+// and deletes it. As redis expires keys in milliseconds, a ttl under 1ms is rounded up to 1ms, and a
+// non-positive ttl is replaced by defaultRedisWriteTTL.
+func WithRedisWriteCheck(ttl time.Duration) RedisOption {
+	return func(m *RedisModule) {
+		switch {
+		case ttl <= 0:
+			ttl = defaultRedisWriteTTL
+		case ttl < time.Millisecond:
+			ttl = time.Millisecond
+		}
+		m.writeTTL = ttl
+	}
+}
+
+// defaultRedisWriteTTL is the ttl of the key of the write check when the given one is not positive.
+const defaultRedisWriteTTL = 10 * time.Second
+
+// NewRedisModule returns the redis health module. The "ping" check is always executed, the other checks
+// when they are enabled by the options.
+func NewRedisModule(redis RedisClient, enabled bool, options ...RedisOption) *RedisModule {
+	var m = &RedisModule{
 		redis:   redis,
 		enabled: enabled,
 	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
 }
 
 // RedisModule is the health check module for redis.
 type RedisModule struct {
-	redis   RedisClient
-	enabled bool
+	redis          RedisClient
+	enabled        bool
+	writeTTL       time.Duration
+	infoCheck      bool
+	infoThresholds RedisInfoThresholds
+	sentinelMaster string
+	clusterCheck   bool
+
+	mutex                       sync.Mutex
+	lastRejectedConnections     int64
+	rejectedConnectionsObserved bool
 }
 
 // RedisClient is the interface of the redis client.
@@ -28,50 +65,107 @@ type RedisClient interface {
 	Do(cmd string, args ...interface{}) (interface{}, error)
 }
 
-type redisReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// RedisContextClient is the interface of a redis client whose commands honour the context, e.g. the redigo
+// connections. When the RedisClient implements it, DoContext is used instead of Do.
+type RedisContextClient interface {
+	DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error)
 }
 
-// HealthCheck executes the desired influx health check.
-func (m *RedisModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+// HealthCheckNames returns the names of the redis health checks.
+func (m *RedisModule) HealthCheckNames() []string {
+	var names = []string{"ping"}
+	if m.writeTTL > 0 {
+		names = append(names, "write")
+	}
+	if m.infoCheck {
+		names = append(names, "info")
+	}
+	if m.sentinelMaster != "" {
+		names = append(names, "sentinel")
+	}
+	if m.clusterCheck {
+		names = append(names, "cluster")
+	}
+	return names
+}
+
+// HealthCheck executes the desired redis health check.
+func (m *RedisModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired redis health check and returns its results.
+func (m *RedisModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]influxReport{{Name: "redis", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("redis"), nil
 	}
 
-	var reports []redisReport
-	switch name {
-	case "":
-		reports = append(reports, m.redisPing())
-	case "ping":
-		reports = append(reports, m.redisPing())
-	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown redis health check name: %v", name))
+	var checks = map[string]func(context.Context) CheckResult{
+		"ping":     m.redisPing,
+		"write":    m.redisWrite,
+		"info":     m.redisInfo,
+		"sentinel": m.redisSentinel,
+		"cluster":  m.redisCluster,
 	}
 
-	return json.MarshalIndent(reports, "", "  ")
+	return runNamedChecks(ctx, name, m.HealthCheckNames(), checks)
+}
+
+func (m *RedisModule) redisPing(ctx context.Context) CheckResult {
+	return runCheck(ctx, "ping", func(ctx context.Context) error {
+		if _, err := m.do(ctx, "PING"); err != nil {
+			return errors.Wrap(err, "could not ping redis")
+		}
+		return nil
+	})
 }
 
-func (m *RedisModule) redisPing() redisReport {
-	var name = "ping"
-	var status = OK
+func (m *RedisModule) redisWrite(ctx context.Context) CheckResult {
+	return runCheck(ctx, "write", func(ctx context.Context) error {
+		var key = fmt.Sprintf("healthcheck:%d:%d", time.Now().UnixNano(), rand.Int63())
+		var value = fmt.Sprintf("%d", rand.Int63())
 
-	var now = time.Now()
-	var _, err = m.redis.Do("PING")
-	var duration = time.Since(now)
+		if _, err := m.do(ctx, "SET", key, value, "PX", m.writeTTL.Milliseconds()); err != nil {
+			return errors.Wrap(err, "set failed")
+		}
 
-	if err != nil {
-		status = KO
-		err = errors.Wrap(err, "could not ping redis")
+		var reply, err = m.do(ctx, "GET", key)
+		if err != nil {
+			return errors.Wrap(err, "get failed")
+		}
+		var read string
+		read, err = redisString(reply)
+		if err != nil {
+			return errors.Wrap(err, "get failed")
+		}
+		if read != value {
+			return errors.Errorf("get failed: value should be '%s' but is '%s'", value, read)
+		}
+
+		if _, err := m.do(ctx, "DEL", key); err != nil {
+			return errors.Wrap(err, "del failed")
+		}
+		return nil
+	})
+}
+
+// redisString converts a redis reply to a string.
+func redisString(reply interface{}) (string, error) {
+	switch r := reply.(type) {
+	case []byte:
+		return string(r), nil
+	case string:
+		return r, nil
+	case nil:
+		return "", errors.New("nil reply")
+	default:
+		return "", errors.Errorf("unexpected reply type %T", reply)
 	}
+}
 
-	return redisReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
+func (m *RedisModule) do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if c, ok := m.redis.(RedisContextClient); ok {
+		return c.DoContext(ctx, cmd, args...)
 	}
+	return m.redis.Do(cmd, args...)
 }
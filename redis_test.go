@@ -137,8 +137,371 @@ func TestRedisUnkownHealthCheck(t *testing.T) {
 		m               = NewRedisModule(mockRedis, enabled)
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+}
+
+func TestRedisTimeout(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var (
+		enabled     = true
+		m           = NewRedisModule(mockRedis, enabled)
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		unblock     = make(chan struct{})
+	)
+	defer cancel()
+	defer close(unblock)
+
+	// The redis client does not honour the context: the check must not wait for it.
+	mockRedis.EXPECT().Do("PING").DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+		<-unblock
+		return nil, nil
+	}).Times(1)
+	var results, err = m.Check(ctx, "ping")
+	assert.Nil(t, err)
+
+	var r = results[0]
+	assert.Equal(t, KO, r.Status)
+	assert.Equal(t, ErrTimeout.Error(), r.Error)
+	assert.True(t, r.Duration < time.Second)
+}
+
+type redisContextClient struct {
+	cmds chan string
+}
+
+func (c *redisContextClient) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("Do must not be called")
+}
+
+func (c *redisContextClient) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	c.cmds <- cmd
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRedisContextClient(t *testing.T) {
+	var (
+		enabled     = true
+		client      = &redisContextClient{cmds: make(chan string, 1)}
+		m           = NewRedisModule(client, enabled)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	cancel()
+	var results, err = m.Check(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "PING", <-client.cmds)
+
+	var r = results[0]
+	assert.Equal(t, KO, r.Status)
+	assert.Equal(t, ErrCanceled.Error(), r.Error)
+}
+
+func TestRedisWrite(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = NewRedisModule(mockRedis, true, WithRedisWriteCheck(10*time.Second))
+	assert.Equal(t, []string{"ping", "write"}, m.HealthCheckNames())
+
+	var key, value interface{}
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("PING").Return("PONG", nil).Times(1),
+		mockRedis.EXPECT().Do("SET", gomock.Any(), gomock.Any(), "PX", int64(10000)).DoAndReturn(func(_ string, args ...interface{}) (interface{}, error) {
+			key, value = args[0], args[1]
+			return "OK", nil
+		}).Times(1),
+		mockRedis.EXPECT().Do("GET", gomock.Any()).DoAndReturn(func(_ string, args ...interface{}) (interface{}, error) {
+			assert.Equal(t, key, args[0])
+			return []byte(value.(string)), nil
+		}).Times(1),
+		mockRedis.EXPECT().Do("DEL", gomock.Any()).DoAndReturn(func(_ string, args ...interface{}) (interface{}, error) {
+			assert.Equal(t, key, args[0])
+			return int64(1), nil
+		}).Times(1),
+	)
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "write", results[1].Name)
+	assert.Equal(t, OK, results[1].Status)
+	assert.Contains(t, key, "healthcheck:")
+
+	// A ttl under 1ms is rounded up, as PX 0 is invalid.
+	m = NewRedisModule(mockRedis, true, WithRedisWriteCheck(time.Microsecond))
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("SET", gomock.Any(), gomock.Any(), "PX", int64(1)).Return("OK", nil).Times(1),
+		mockRedis.EXPECT().Do("GET", gomock.Any()).Return(nil, nil).Times(1),
+	)
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Equal(t, "get failed: nil reply", results[0].Error)
+
+	// A non-positive ttl is replaced by the default one, the write check is still enabled.
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		m = NewRedisModule(mockRedis, true, WithRedisWriteCheck(ttl))
+		assert.Equal(t, []string{"ping", "write"}, m.HealthCheckNames())
+		gomock.InOrder(
+			mockRedis.EXPECT().Do("SET", gomock.Any(), gomock.Any(), "PX", int64(10000)).Return("OK", nil).Times(1),
+			mockRedis.EXPECT().Do("GET", gomock.Any()).Return(nil, nil).Times(1),
+		)
+		results, err = m.Check(context.Background(), "write")
+		assert.Nil(t, err)
+		assert.Equal(t, "get failed: nil reply", results[0].Error)
 	}
-	assert.Panics(t, f)
+
+	// The write check is not enabled by default.
+	_, err = NewRedisModule(mockRedis, true).Check(context.Background(), "write")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+}
+
+func TestRedisWriteFailures(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = NewRedisModule(mockRedis, true, WithRedisWriteCheck(time.Second))
+
+	var tsts = []struct {
+		set, get, del          interface{}
+		setErr, getErr, delErr error
+		err                    string
+	}{
+		{setErr: fmt.Errorf("OOM command not allowed"), err: "set failed: OOM command not allowed"},
+		{set: "OK", getErr: fmt.Errorf("fail"), err: "get failed: fail"},
+		{set: "OK", get: nil, err: "get failed: nil reply"},
+		{set: "OK", get: []byte("other"), err: "get failed: value should be"},
+		{set: "OK", get: int64(1), err: "get failed: unexpected reply type int64"},
+		{set: "OK", get: "", delErr: fmt.Errorf("READONLY"), err: "del failed: READONLY"},
+	}
+
+	for _, tst := range tsts {
+		var value string
+		mockRedis.EXPECT().Do("SET", gomock.Any(), gomock.Any(), "PX", int64(1000)).DoAndReturn(func(_ string, args ...interface{}) (interface{}, error) {
+			value = args[1].(string)
+			return tst.set, tst.setErr
+		}).Times(1)
+		if tst.setErr == nil {
+			mockRedis.EXPECT().Do("GET", gomock.Any()).DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+				if tst.get == "" {
+					return value, nil
+				}
+				return tst.get, tst.getErr
+			}).Times(1)
+		}
+		if tst.delErr != nil {
+			mockRedis.EXPECT().Do("DEL", gomock.Any()).Return(nil, tst.delErr).Times(1)
+		}
+
+		var results, err = m.Check(context.Background(), "write")
+		assert.Nil(t, err)
+		assert.Equal(t, KO, results[0].Status)
+		assert.Contains(t, results[0].Error, tst.err)
+	}
+}
+
+func TestRedisInfo(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = NewRedisModule(mockRedis, true, WithRedisInfoCheck(RedisInfoThresholds{MaxRejectedConnections: 10}))
+	assert.Equal(t, []string{"ping", "info"}, m.HealthCheckNames())
+
+	var info = "# Clients\r\nconnected_clients:2\r\nblocked_clients:0\r\n\r\n# Stats\r\nrejected_connections:%d\r\n\r\n# Replication\r\nrole:master\r\n"
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("INFO").Return([]byte(fmt.Sprintf(info, 17)), nil).Times(1),
+		mockRedis.EXPECT().Do("INFO").Return([]byte(fmt.Sprintf(info, 32)), nil).Times(1),
+		mockRedis.EXPECT().Do("INFO").Return(nil, fmt.Errorf("fail")).Times(1),
+	)
+
+	// The connections rejected before the first check are not counted.
+	var results, err = m.Check(context.Background(), "info")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+	assert.Equal(t, "master", results[0].Details["role"])
+	assert.Equal(t, int64(2), results[0].Details["connected_clients"])
+
+	// 15 connections rejected since the previous check.
+	results, err = m.Check(context.Background(), "info")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "15 connections rejected since the previous check, more than 10", results[0].Error)
+
+	results, err = m.Check(context.Background(), "info")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "could not get redis info: fail", results[0].Error)
+	assert.Nil(t, results[0].Details)
+}
+
+// redisArray builds a redis array reply of bulk strings, as returned by redigo.
+func redisArray(values ...string) []interface{} {
+	var reply = []interface{}{}
+	for _, v := range values {
+		reply = append(reply, []byte(v))
+	}
+	return reply
+}
+
+func TestRedisSentinel(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = NewRedisModule(mockRedis, true, WithRedisSentinelCheck("mymaster"))
+	assert.Equal(t, []string{"ping", "sentinel"}, m.HealthCheckNames())
+
+	var (
+		addr   = redisArray("10.0.0.11", "6379")
+		master = func(flags string, others string) []interface{} {
+			return redisArray("name", "mymaster", "ip", "10.0.0.11", "port", "6379", "flags", flags, "num-other-sentinels", others, "quorum", "2")
+		}
+		replica = func(flags string) []interface{} {
+			return redisArray("name", "10.0.0.12:6379", "ip", "10.0.0.12", "port", "6379", "flags", flags)
+		}
+	)
+
+	var (
+		quorumOK = "OK 3 usable Sentinels. Quorum and failover authorization can be reached"
+		noQuorum = fmt.Errorf("NOQUORUM 1 usable Sentinels. Not enough available Sentinels to reach the specified quorum for this master")
+	)
+
+	var tsts = []struct {
+		master   []interface{}
+		replicas []interface{}
+		quorum   error
+		status   Status
+		err      string
+	}{
+		{master("master", "2"), []interface{}{replica("slave"), replica("slave,s_down")}, nil, OK, ""},
+		{master("master,o_down", "2"), []interface{}{replica("slave")}, nil, KO, "master mymaster is down: master,o_down"},
+		{master("master", "0"), []interface{}{replica("slave")}, noQuorum, KO, "quorum of master mymaster cannot be reached: " + noQuorum.Error()},
+		{master("master,failover_in_progress", "2"), []interface{}{replica("slave")}, nil, Degraded, "failover of master mymaster in progress"},
+		{master("master", "2"), []interface{}{replica("slave,disconnected")}, nil, Degraded, "no healthy replica of master mymaster"},
+	}
+
+	for _, tst := range tsts {
+		var ckquorum interface{} = quorumOK
+		if tst.quorum != nil {
+			ckquorum = nil
+		}
+		gomock.InOrder(
+			mockRedis.EXPECT().Do("SENTINEL", "get-master-addr-by-name", "mymaster").Return(addr, nil).Times(1),
+			mockRedis.EXPECT().Do("SENTINEL", "master", "mymaster").Return(tst.master, nil).Times(1),
+			mockRedis.EXPECT().Do("SENTINEL", "replicas", "mymaster").Return(tst.replicas, nil).Times(1),
+			mockRedis.EXPECT().Do("SENTINEL", "CKQUORUM", "mymaster").Return(ckquorum, tst.quorum).Times(1),
+		)
+
+		var results, err = m.Check(context.Background(), "sentinel")
+		assert.Nil(t, err)
+		assert.Equal(t, tst.status, results[0].Status)
+		assert.Equal(t, tst.err, results[0].Error)
+		assert.Equal(t, "10.0.0.11:6379", results[0].Details["master"])
+		assert.Equal(t, 2, results[0].Details["quorum"])
+		if tst.quorum == nil {
+			assert.Equal(t, quorumOK, results[0].Details["ckquorum"])
+		} else {
+			assert.Equal(t, tst.quorum.Error(), results[0].Details["ckquorum"])
+		}
+	}
+
+	// Unknown master.
+	mockRedis.EXPECT().Do("SENTINEL", "get-master-addr-by-name", "mymaster").Return(nil, nil).Times(1)
+	var results, err = m.Check(context.Background(), "sentinel")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "master mymaster is unknown", results[0].Error)
+
+	// Failures.
+	mockRedis.EXPECT().Do("SENTINEL", "get-master-addr-by-name", "mymaster").Return(nil, fmt.Errorf("fail")).Times(1)
+	results, _ = m.Check(context.Background(), "sentinel")
+	assert.Equal(t, "could not get master address: fail", results[0].Error)
+
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("SENTINEL", "get-master-addr-by-name", "mymaster").Return(addr, nil).Times(1),
+		mockRedis.EXPECT().Do("SENTINEL", "master", "mymaster").Return(redisArray("flags"), nil).Times(1),
+	)
+	results, _ = m.Check(context.Background(), "sentinel")
+	assert.Equal(t, "could not get master state: odd number of elements in key value reply", results[0].Error)
+
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("SENTINEL", "get-master-addr-by-name", "mymaster").Return(addr, nil).Times(1),
+		mockRedis.EXPECT().Do("SENTINEL", "master", "mymaster").Return(master("master", "2"), nil).Times(1),
+		mockRedis.EXPECT().Do("SENTINEL", "replicas", "mymaster").Return("OK", nil).Times(1),
+	)
+	results, _ = m.Check(context.Background(), "sentinel")
+	assert.Equal(t, "could not get replicas: unexpected reply type string", results[0].Error)
+}
+
+func TestRedisCluster(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = NewRedisModule(mockRedis, true, WithRedisClusterCheck())
+	assert.Equal(t, []string{"ping", "cluster"}, m.HealthCheckNames())
+
+	var info = func(state string, assigned, pfail, fail int) []byte {
+		return []byte(fmt.Sprintf("cluster_enabled:1\r\ncluster_state:%s\r\ncluster_slots_assigned:%d\r\ncluster_slots_ok:%d\r\ncluster_slots_pfail:%d\r\ncluster_slots_fail:%d\r\ncluster_known_nodes:6\r\ncluster_size:3\r\n",
+			state, assigned, assigned-pfail-fail, pfail, fail))
+	}
+	var nodes = func(replicaFlags, replicaLink string) []byte {
+		return []byte("" +
+			"07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004 " + replicaFlags + " e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 " + replicaLink + "\n" +
+			"67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002 master - 0 1426238316232 2 connected 5461-10922\n" +
+			"292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003 master - 0 1426238318243 3 connected 10923-16383\n" +
+			"e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-5460\n")
+	}
+
+	var tsts = []struct {
+		info   []byte
+		nodes  []byte
+		status Status
+		err    string
+	}{
+		{info("ok", 16384, 0, 0), nodes("slave", "connected"), OK, ""},
+		{info("fail", 16384, 0, 0), nodes("slave", "connected"), KO, "cluster state is fail"},
+		{info("ok", 16000, 0, 0), nodes("slave", "connected"), KO, "16000 of 16384 slots are assigned"},
+		{info("ok", 16384, 0, 10), nodes("slave", "connected"), KO, "10 slots are failing"},
+		{info("ok", 16384, 10, 0), nodes("slave", "connected"), Degraded, "10 slots are possibly failing"},
+		{info("ok", 16384, 0, 0), nodes("slave,fail?", "connected"), Degraded, "nodes [127.0.0.1:30004@31004] are failing or disconnected"},
+		{info("ok", 16384, 0, 0), nodes("slave", "disconnected"), Degraded, "nodes [127.0.0.1:30004@31004] are failing or disconnected"},
+	}
+
+	for _, tst := range tsts {
+		gomock.InOrder(
+			mockRedis.EXPECT().Do("CLUSTER", "INFO").Return(tst.info, nil).Times(1),
+			mockRedis.EXPECT().Do("CLUSTER", "NODES").Return(tst.nodes, nil).Times(1),
+		)
+
+		var results, err = m.Check(context.Background(), "cluster")
+		assert.Nil(t, err)
+		assert.Equal(t, tst.status, results[0].Status)
+		assert.Equal(t, tst.err, results[0].Error)
+		assert.Equal(t, 3, results[0].Details["masters"])
+		assert.Equal(t, 1, results[0].Details["replicas"])
+		assert.Equal(t, int64(3), results[0].Details["size"])
+	}
+
+	// Failures.
+	mockRedis.EXPECT().Do("CLUSTER", "INFO").Return(nil, fmt.Errorf("ERR This instance has cluster support disabled")).Times(1)
+	var results, err = m.Check(context.Background(), "cluster")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, "could not get cluster info: ERR This instance has cluster support disabled", results[0].Error)
+
+	gomock.InOrder(
+		mockRedis.EXPECT().Do("CLUSTER", "INFO").Return(info("ok", 16384, 0, 0), nil).Times(1),
+		mockRedis.EXPECT().Do("CLUSTER", "NODES").Return(nil, nil).Times(1),
+	)
+	results, _ = m.Check(context.Background(), "cluster")
+	assert.Equal(t, "could not get cluster nodes: nil reply", results[0].Error)
 }
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedisInfoThresholds are the thresholds of the redis "info" check, from which the check is KO. Zero
+// disables a threshold.
+type RedisInfoThresholds struct {
+	// MaxMemoryRatio is the maximum ratio of used_memory to maxmemory, e.g. 0.9. It applies only when
+	// maxmemory is set.
+	MaxMemoryRatio float64
+	// MaxReplicationLag is the maximum number of seconds since the last interaction of a replica with its
+	// master.
+	MaxReplicationLag int64
+	// MaxConnectedClients is the maximum number of connected clients.
+	MaxConnectedClients int64
+	// MaxBlockedClients is the maximum number of clients blocked by a blocking command.
+	MaxBlockedClients int64
+	// MaxRejectedConnections is the maximum number of connections rejected since the previous check.
+	MaxRejectedConnections int64
+}
+
+// WithRedisInfoCheck enables the "info" check, that runs INFO and reports the role, the replication, the
+// memory, the clients and the persistence status of redis. The check is KO when a replica is disconnected
+// from its master, when the last RDB save or AOF write failed, or when a threshold is exceeded.
+func WithRedisInfoCheck(thresholds RedisInfoThresholds) RedisOption {
+	return func(m *RedisModule) {
+		m.infoCheck = true
+		m.infoThresholds = thresholds
+	}
+}
+
+func (m *RedisModule) redisInfo(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "info", func(ctx context.Context) (map[string]interface{}, error) {
+		var reply, err = m.do(ctx, "INFO")
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get redis info")
+		}
+
+		var info string
+		info, err = redisString(reply)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get redis info")
+		}
+
+		var values = parseRedisInfo(info)
+		var details = redisInfoDetails(values)
+
+		// The first observation is the baseline of the rejected connections, not counted against the threshold.
+		m.mutex.Lock()
+		var rejected int64
+		if m.rejectedConnectionsObserved {
+			rejected = details["rejected_connections"].(int64) - m.lastRejectedConnections
+		}
+		m.lastRejectedConnections = details["rejected_connections"].(int64)
+		m.rejectedConnectionsObserved = true
+		m.mutex.Unlock()
+
+		return details, m.checkInfo(details, rejected)
+	})
+}
+
+func (m *RedisModule) checkInfo(details map[string]interface{}, rejected int64) error {
+	var t = m.infoThresholds
+
+	if details["role"] == "slave" && details["master_link_status"] != "up" {
+		return errors.Errorf("replication link to master is %v", details["master_link_status"])
+	}
+	if s := details["rdb_last_bgsave_status"]; s != "" && s != "ok" {
+		return errors.Errorf("last rdb save status is %v", s)
+	}
+	if s := details["aof_last_write_status"]; s != "" && s != "ok" {
+		return errors.Errorf("last aof write status is %v", s)
+	}
+
+	var used, max = details["used_memory"].(int64), details["maxmemory"].(int64)
+	if t.MaxMemoryRatio > 0 && max > 0 && float64(used)/float64(max) > t.MaxMemoryRatio {
+		return errors.Errorf("%d bytes of memory used out of %d, more than %.0f%%", used, max, 100*t.MaxMemoryRatio)
+	}
+	if lag, ok := details["replication_lag"].(int64); ok && t.MaxReplicationLag > 0 && lag > t.MaxReplicationLag {
+		return errors.Errorf("replication lag of %ds, more than %ds", lag, t.MaxReplicationLag)
+	}
+	if c := details["connected_clients"].(int64); t.MaxConnectedClients > 0 && c > t.MaxConnectedClients {
+		return errors.Errorf("%d connected clients, more than %d", c, t.MaxConnectedClients)
+	}
+	if c := details["blocked_clients"].(int64); t.MaxBlockedClients > 0 && c > t.MaxBlockedClients {
+		return errors.Errorf("%d blocked clients, more than %d", c, t.MaxBlockedClients)
+	}
+	if t.MaxRejectedConnections > 0 && rejected > t.MaxRejectedConnections {
+		return errors.Errorf("%d connections rejected since the previous check, more than %d", rejected, t.MaxRejectedConnections)
+	}
+	return nil
+}
+
+// redisInfoDetails returns the details of the info check. The numbers are int64, 0 when missing.
+func redisInfoDetails(values map[string]string) map[string]interface{} {
+	var number = func(key string) int64 {
+		var n, _ = strconv.ParseInt(values[key], 10, 64)
+		return n
+	}
+
+	var details = map[string]interface{}{
+		"role":                   values["role"],
+		"used_memory":            number("used_memory"),
+		"maxmemory":              number("maxmemory"),
+		"connected_clients":      number("connected_clients"),
+		"blocked_clients":        number("blocked_clients"),
+		"rejected_connections":   number("rejected_connections"),
+		"rdb_last_bgsave_status": values["rdb_last_bgsave_status"],
+		"aof_last_write_status":  values["aof_last_write_status"],
+	}
+	if values["role"] == "slave" {
+		details["master_link_status"] = values["master_link_status"]
+		details["replication_lag"] = number("master_last_io_seconds_ago")
+	}
+	return details
+}
+
+// parseRedisInfo parses the output of the INFO command. The sections are flattened, as the keys are
+// unique across sections.
+func parseRedisInfo(info string) map[string]string {
+	var values = map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var kv = strings.SplitN(line, ":", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+	return values
+}
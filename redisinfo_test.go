@@ -0,0 +1,163 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Captured INFO outputs, shortened.
+var (
+	redisMasterInfo = strings.ReplaceAll(`# Server
+redis_version:7.2.4
+redis_mode:standalone
+os:Linux 6.1.0 x86_64
+tcp_port:6379
+uptime_in_seconds:86532
+
+# Clients
+connected_clients:12
+cluster_connections:0
+maxclients:10000
+blocked_clients:1
+tracking_clients:0
+
+# Memory
+used_memory:1853440
+used_memory_human:1.77M
+used_memory_peak:2012344
+maxmemory:104857600
+maxmemory_human:100.00M
+maxmemory_policy:noeviction
+
+# Persistence
+loading:0
+rdb_changes_since_last_save:3
+rdb_bgsave_in_progress:0
+rdb_last_save_time:1718012345
+rdb_last_bgsave_status:ok
+aof_enabled:0
+aof_rewrite_in_progress:0
+aof_last_bgrewrite_status:ok
+aof_last_write_status:ok
+
+# Stats
+total_connections_received:2351
+total_commands_processed:120384
+rejected_connections:0
+expired_keys:52
+
+# Replication
+role:master
+connected_slaves:1
+slave0:ip=10.0.0.12,port=6379,state=online,offset=1834215,lag=0
+master_failover_state:no-failover
+master_repl_offset:1834215
+`, "\n", "\r\n")
+
+	redisReplicaInfo = strings.ReplaceAll(`# Server
+redis_version:6.2.14
+redis_mode:standalone
+
+# Clients
+connected_clients:3
+blocked_clients:0
+
+# Memory
+used_memory:98566144
+maxmemory:104857600
+
+# Persistence
+rdb_last_bgsave_status:err
+aof_enabled:1
+aof_last_write_status:ok
+
+# Stats
+rejected_connections:17
+
+# Replication
+role:slave
+master_host:10.0.0.11
+master_port:6379
+master_link_status:down
+master_last_io_seconds_ago:-1
+master_sync_in_progress:0
+slave_repl_offset:1834215
+master_link_down_since_seconds:42
+`, "\n", "\r\n")
+)
+
+func TestParseRedisInfo(t *testing.T) {
+	var values = parseRedisInfo(redisMasterInfo)
+	assert.Equal(t, "7.2.4", values["redis_version"])
+	assert.Equal(t, "master", values["role"])
+	assert.Equal(t, "ip=10.0.0.12,port=6379,state=online,offset=1834215,lag=0", values["slave0"])
+	assert.Equal(t, "Linux 6.1.0 x86_64", values["os"])
+	assert.NotContains(t, values, "# Server")
+	assert.NotContains(t, values, "")
+
+	assert.Empty(t, parseRedisInfo(""))
+}
+
+func TestRedisInfoDetails(t *testing.T) {
+	var details = redisInfoDetails(parseRedisInfo(redisMasterInfo))
+	assert.Equal(t, map[string]interface{}{
+		"role":                   "master",
+		"used_memory":            int64(1853440),
+		"maxmemory":              int64(104857600),
+		"connected_clients":      int64(12),
+		"blocked_clients":        int64(1),
+		"rejected_connections":   int64(0),
+		"rdb_last_bgsave_status": "ok",
+		"aof_last_write_status":  "ok",
+	}, details)
+
+	details = redisInfoDetails(parseRedisInfo(redisReplicaInfo))
+	assert.Equal(t, "slave", details["role"])
+	assert.Equal(t, "down", details["master_link_status"])
+	assert.Equal(t, int64(-1), details["replication_lag"])
+	assert.Equal(t, int64(17), details["rejected_connections"])
+	assert.Equal(t, "err", details["rdb_last_bgsave_status"])
+}
+
+func TestCheckRedisInfo(t *testing.T) {
+	var master = redisInfoDetails(parseRedisInfo(redisMasterInfo))
+	var replica = redisInfoDetails(parseRedisInfo(redisReplicaInfo))
+
+	var tsts = []struct {
+		thresholds RedisInfoThresholds
+		details    map[string]interface{}
+		rejected   int64
+		err        string
+	}{
+		{RedisInfoThresholds{}, master, 0, ""},
+		{RedisInfoThresholds{MaxMemoryRatio: 0.9, MaxConnectedClients: 12, MaxBlockedClients: 1, MaxRejectedConnections: 1}, master, 1, ""},
+		{RedisInfoThresholds{MaxMemoryRatio: 0.01}, master, 0, "1853440 bytes of memory used out of 104857600, more than 1%"},
+		{RedisInfoThresholds{MaxConnectedClients: 10}, master, 0, "12 connected clients, more than 10"},
+		{RedisInfoThresholds{MaxBlockedClients: -1}, master, 0, ""},
+		{RedisInfoThresholds{MaxRejectedConnections: 5}, master, 6, "6 connections rejected since the previous check, more than 5"},
+		{RedisInfoThresholds{}, replica, 0, "replication link to master is down"},
+	}
+
+	for _, tst := range tsts {
+		var m = &RedisModule{infoThresholds: tst.thresholds}
+		var err = m.checkInfo(tst.details, tst.rejected)
+		if tst.err == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, tst.err)
+		}
+	}
+
+	// Persistence and lag.
+	var m = &RedisModule{infoThresholds: RedisInfoThresholds{MaxReplicationLag: 10}}
+	replica["master_link_status"] = "up"
+	assert.EqualError(t, m.checkInfo(replica, 0), "last rdb save status is err")
+	replica["rdb_last_bgsave_status"] = "ok"
+	replica["aof_last_write_status"] = "err"
+	assert.EqualError(t, m.checkInfo(replica, 0), "last aof write status is err")
+	replica["aof_last_write_status"] = "ok"
+	replica["replication_lag"] = int64(30)
+	assert.EqualError(t, m.checkInfo(replica, 0), "replication lag of 30s, more than 10s")
+}
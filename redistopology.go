@@ -0,0 +1,249 @@
+package common
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// redisClusterSlots is the number of hash slots of a redis cluster.
+const redisClusterSlots = 16384
+
+// WithRedisSentinelCheck enables the "sentinel" check, for a RedisClient connected to a sentinel. It reports
+// the address and the state of the monitored master, the quorum and the replicas. The check is KO when the
+// master is unknown or down, or when SENTINEL CKQUORUM reports that the sentinels cannot reach the quorum or
+// authorize a failover, and Degraded during a failover or when no replica is healthy.
+func WithRedisSentinelCheck(masterName string) RedisOption {
+	return func(m *RedisModule) {
+		m.sentinelMaster = masterName
+	}
+}
+
+// WithRedisClusterCheck enables the "cluster" check, for a RedisClient connected to a cluster node. It
+// reports the cluster state, the slots coverage and the nodes. The check is KO when the cluster state is
+// not ok or when slots are not covered, and Degraded when slots or nodes are possibly failing.
+func WithRedisClusterCheck() RedisOption {
+	return func(m *RedisModule) {
+		m.clusterCheck = true
+	}
+}
+
+func (m *RedisModule) redisSentinel(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "sentinel", func(ctx context.Context) (map[string]interface{}, error) {
+		var reply, err = m.do(ctx, "SENTINEL", "get-master-addr-by-name", m.sentinelMaster)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get master address")
+		}
+		if reply == nil {
+			return nil, errors.Errorf("master %s is unknown", m.sentinelMaster)
+		}
+
+		var addr []string
+		addr, err = redisStrings(reply)
+		if err != nil || len(addr) != 2 {
+			return nil, errors.Errorf("could not get master address: unexpected reply %v", reply)
+		}
+
+		var master map[string]string
+		reply, err = m.do(ctx, "SENTINEL", "master", m.sentinelMaster)
+		if err == nil {
+			master, err = redisMap(reply)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get master state")
+		}
+
+		reply, err = m.do(ctx, "SENTINEL", "replicas", m.sentinelMaster)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get replicas")
+		}
+		var replicas []interface{}
+		replicas, err = redisArray(reply)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get replicas")
+		}
+
+		var healthy int
+		for _, r := range replicas {
+			var replica, err = redisMap(r)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not get replicas")
+			}
+			if !redisFlagged(replica["flags"], "s_down", "o_down", "disconnected") {
+				healthy++
+			}
+		}
+
+		// CKQUORUM fails when the usable sentinels cannot reach the quorum or the majority to authorize a
+		// failover, its message tells why.
+		var ckquorum string
+		reply, err = m.do(ctx, "SENTINEL", "CKQUORUM", m.sentinelMaster)
+		if err == nil {
+			ckquorum, err = redisString(reply)
+		}
+		var quorumErr = err
+		if quorumErr != nil {
+			ckquorum = quorumErr.Error()
+		}
+
+		var (
+			quorum, _ = strconv.Atoi(master["quorum"])
+			others, _ = strconv.Atoi(master["num-other-sentinels"])
+			sentinels = others + 1
+			failover  = redisFlagged(master["flags"], "failover_in_progress")
+			details   = map[string]interface{}{
+				"master":               addr[0] + ":" + addr[1],
+				"flags":                master["flags"],
+				"quorum":               quorum,
+				"sentinels":            sentinels,
+				"ckquorum":             ckquorum,
+				"failover_in_progress": failover,
+				"replicas":             len(replicas),
+				"healthy_replicas":     healthy,
+			}
+		)
+
+		switch {
+		case redisFlagged(master["flags"], "s_down", "o_down"):
+			return details, errors.Errorf("master %s is down: %s", m.sentinelMaster, master["flags"])
+		case quorumErr != nil:
+			return details, errors.Wrapf(quorumErr, "quorum of master %s cannot be reached", m.sentinelMaster)
+		case failover:
+			return details, degraded(errors.Errorf("failover of master %s in progress", m.sentinelMaster))
+		case healthy == 0:
+			return details, degraded(errors.Errorf("no healthy replica of master %s", m.sentinelMaster))
+		}
+		return details, nil
+	})
+}
+
+func (m *RedisModule) redisCluster(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "cluster", func(ctx context.Context) (map[string]interface{}, error) {
+		var info, nodes string
+		var reply, err = m.do(ctx, "CLUSTER", "INFO")
+		if err == nil {
+			info, err = redisString(reply)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get cluster info")
+		}
+
+		reply, err = m.do(ctx, "CLUSTER", "NODES")
+		if err == nil {
+			nodes, err = redisString(reply)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get cluster nodes")
+		}
+
+		var values = parseRedisInfo(info)
+		var number = func(key string) int64 {
+			var n, _ = strconv.ParseInt(values[key], 10, 64)
+			return n
+		}
+
+		var details = map[string]interface{}{
+			"state":          values["cluster_state"],
+			"slots_assigned": number("cluster_slots_assigned"),
+			"slots_ok":       number("cluster_slots_ok"),
+			"slots_pfail":    number("cluster_slots_pfail"),
+			"slots_fail":     number("cluster_slots_fail"),
+			"known_nodes":    number("cluster_known_nodes"),
+			"size":           number("cluster_size"),
+		}
+
+		var masters, replicas int
+		var failing = []string{}
+		for _, line := range strings.Split(nodes, "\n") {
+			// <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slots>...
+			var fields = strings.Fields(line)
+			if len(fields) < 8 {
+				continue
+			}
+			switch {
+			case redisFlagged(fields[2], "master"):
+				masters++
+			case redisFlagged(fields[2], "slave"):
+				replicas++
+			}
+			if redisFlagged(fields[2], "fail", "fail?") || fields[7] != "connected" {
+				failing = append(failing, fields[1])
+			}
+		}
+		details["masters"] = masters
+		details["replicas"] = replicas
+		details["failing_nodes"] = failing
+
+		switch {
+		case values["cluster_state"] != "ok":
+			return details, errors.Errorf("cluster state is %s", values["cluster_state"])
+		case details["slots_assigned"].(int64) < redisClusterSlots:
+			return details, errors.Errorf("%d of %d slots are assigned", details["slots_assigned"], redisClusterSlots)
+		case details["slots_fail"].(int64) > 0:
+			return details, errors.Errorf("%d slots are failing", details["slots_fail"])
+		case details["slots_pfail"].(int64) > 0:
+			return details, degraded(errors.Errorf("%d slots are possibly failing", details["slots_pfail"]))
+		case len(failing) > 0:
+			return details, degraded(errors.Errorf("nodes %v are failing or disconnected", failing))
+		}
+		return details, nil
+	})
+}
+
+// redisFlagged returns true if the comma separated flags contain one of the expected flags.
+func redisFlagged(flags string, expected ...string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		for _, e := range expected {
+			if f == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redisArray converts a redis array reply.
+func redisArray(reply interface{}) ([]interface{}, error) {
+	var values, ok = reply.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected reply type %T", reply)
+	}
+	return values, nil
+}
+
+// redisStrings converts a redis array reply of strings.
+func redisStrings(reply interface{}) ([]string, error) {
+	var values, err = redisArray(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	var strs = make([]string, 0, len(values))
+	for _, v := range values {
+		var s, err = redisString(v)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+// redisMap converts a redis array reply of alternating keys and values.
+func redisMap(reply interface{}) (map[string]string, error) {
+	var strs, err = redisStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(strs)%2 != 0 {
+		return nil, errors.New("odd number of elements in key value reply")
+	}
+
+	var values = map[string]string{}
+	for i := 0; i < len(strs); i += 2 {
+		values[strs[i]] = strs[i+1]
+	}
+	return values, nil
+}
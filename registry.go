@@ -0,0 +1,187 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewRegistry returns an empty health check registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		modules: map[string]HealthChecker{},
+		probes:  map[string]Probe{},
+	}
+}
+
+// Registry is a HealthChecker that aggregates named health check modules.
+// The health check name "" executes all the checks of all the modules, "<module>" all the checks of
+// one module, and "<module>/<check>" a single check of one module, e.g. "redis/ping".
+type Registry struct {
+	names          []string
+	modules        map[string]HealthChecker
+	probes         map[string]Probe
+	maxParallelism int
+	timeout        time.Duration
+
+	startupMutex   sync.Mutex
+	startupResults []CheckResult
+}
+
+// Register adds the module to the registry under the given name. The module participates in the given
+// probes, or in all of them if none is given. Registering the same name twice replaces the previous module.
+// Register must not be called concurrently with HealthCheck.
+func (r *Registry) Register(name string, module HealthChecker, probes ...Probe) {
+	if _, ok := r.modules[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.modules[name] = module
+
+	var p Probe
+	for _, probe := range probes {
+		p |= probe
+	}
+	if p == 0 {
+		p = LivenessProbe | ReadinessProbe | StartupProbe
+	}
+	r.probes[name] = p
+}
+
+// HealthCheckNames returns the names of the registered modules, in registration order, each followed by the
+// names "<module>/<check>" of its health checks if the module implements HealthCheckNamer.
+func (r *Registry) HealthCheckNames() []string {
+	var names []string
+	for _, moduleName := range r.names {
+		names = append(names, moduleName)
+		for _, checkName := range healthCheckNames(r.modules[moduleName]) {
+			names = append(names, moduleName+"/"+checkName)
+		}
+	}
+	return names
+}
+
+// SetMaxParallelism sets the maximum number of checks executed concurrently, across all the modules (see
+// WithMaxParallelism). By default, everything is executed sequentially. The results are in registration
+// order regardless of the parallelism.
+func (r *Registry) SetMaxParallelism(n int) {
+	r.maxParallelism = n
+}
+
+// SetTimeout sets the global deadline of the health checks. The modules that did not complete in time are
+// reported KO with ErrTimeout. By default, there is no deadline other than the one of the caller's context.
+func (r *Registry) SetTimeout(timeout time.Duration) {
+	r.timeout = timeout
+}
+
+// HealthCheck executes the desired health checks and returns a report with the overall status and the
+// results of each module, keyed by module name.
+func (r *Registry) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var results, err = r.Check(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(NewReport(results), "", "  ")
+}
+
+// Check executes the desired health checks and returns their results, in registration order, with
+// their Module set.
+func (r *Registry) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	ctx, cancel := r.checkContext(ctx)
+	defer cancel()
+
+	if name == "" {
+		return r.checkModules(ctx, r.names), nil
+	}
+
+	var moduleName, checkName = splitHCName(name)
+	if _, ok := r.modules[moduleName]; !ok {
+		return nil, &ErrInvalidHCName{name}
+	}
+
+	var results, err = r.checkModule(ctx, moduleName, checkName)
+	var invalid *ErrInvalidHCName
+	if errors.As(err, &invalid) {
+		// The module does not know the module name.
+		return nil, &ErrInvalidHCName{name}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withModule(moduleName, results), nil
+}
+
+// checkContext returns the context of the health checks, with the deadline and the parallelism of the registry.
+func (r *Registry) checkContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.maxParallelism > 1 {
+		ctx = WithMaxParallelism(ctx, r.maxParallelism)
+	}
+	if r.timeout > 0 {
+		return context.WithTimeout(ctx, r.timeout)
+	}
+	return ctx, func() {}
+}
+
+// checkModules executes all the checks of the modules. The errors of the modules are reported as KO results.
+func (r *Registry) checkModules(ctx context.Context, names []string) []CheckResult {
+	var moduleResults = make([][]CheckResult, len(names))
+	parallel(ctx, len(names), func(i int) {
+		var results, err = r.checkModule(ctx, names[i], "")
+		if err != nil {
+			results = []CheckResult{{Name: names[i], Status: KO, Error: str(err)}}
+		}
+		moduleResults[i] = withModule(names[i], results)
+	})
+
+	var results []CheckResult
+	for _, mr := range moduleResults {
+		results = append(results, mr...)
+	}
+	return results
+}
+
+// checkModule executes the health check of the module. It does not wait for the module once the context is
+// done: the module is then reported KO with the context error.
+func (r *Registry) checkModule(ctx context.Context, moduleName, checkName string) ([]CheckResult, error) {
+	var module = r.modules[moduleName]
+	if ctx.Done() == nil {
+		return check(ctx, module, checkName)
+	}
+
+	type moduleResults struct {
+		results []CheckResult
+		err     error
+	}
+
+	var c = make(chan moduleResults, 1)
+	go func() {
+		var results, err = check(ctx, module, checkName)
+		c <- moduleResults{results, err}
+	}()
+
+	select {
+	case mr := <-c:
+		return mr.results, mr.err
+	case <-ctx.Done():
+		return []CheckResult{{Name: moduleName, Status: KO, Error: str(contextError(ctx))}}, nil
+	}
+}
+
+func withModule(module string, results []CheckResult) []CheckResult {
+	for i := range results {
+		results[i].Module = module
+	}
+	return results
+}
+
+// splitHCName splits a health check name of the form "<module>/<check>".
+func splitHCName(name string) (string, string) {
+	var idx = strings.Index(name, "/")
+	if idx == -1 {
+		return name, ""
+	}
+	return name[:idx], name[idx+1:]
+}
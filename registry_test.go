@@ -0,0 +1,321 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type registryReport struct {
+	Status  string                       `json:"status"`
+	Modules map[string][]cockroachReport `json:"modules"`
+}
+
+var (
+	okReport = json.RawMessage(`[{"name": "ping", "status": "OK", "duration": "1ms"}]`)
+	koReport = json.RawMessage(`[{"name": "ping", "status": "KO", "duration": "1ms", "error": "fail"}]`)
+)
+
+func TestRegistryAllChecks(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewHealthChecker(mockCtrl)
+	var mockInflux = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", mockRedis)
+	r.Register("influx", mockInflux)
+
+	// All OK.
+	mockRedis.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1)
+	mockInflux.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1)
+	var jsonReport, err = r.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "OK", report.Status)
+	assert.Len(t, report.Modules, 2)
+	assert.Equal(t, "ping", report.Modules["redis"][0].Name)
+	assert.Equal(t, "OK", report.Modules["influx"][0].Status)
+
+	// One module KO.
+	mockRedis.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1)
+	mockInflux.EXPECT().HealthCheck(context.Background(), "").Return(koReport, nil).Times(1)
+	jsonReport, err = r.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "KO", report.Status)
+	assert.Equal(t, "OK", report.Modules["redis"][0].Status)
+	assert.Equal(t, "KO", report.Modules["influx"][0].Status)
+}
+
+func TestRegistryModuleError(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", mockRedis)
+
+	mockRedis.EXPECT().HealthCheck(context.Background(), "").Return(nil, fmt.Errorf("fail")).Times(1)
+	var jsonReport, err = r.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "KO", report.Status)
+
+	var m = report.Modules["redis"][0]
+	assert.Equal(t, "redis", m.Name)
+	assert.Equal(t, "KO", m.Status)
+	assert.Equal(t, "fail", m.Error)
+}
+
+func TestRegistryRouting(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewHealthChecker(mockCtrl)
+	var mockInflux = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("redis", mockRedis)
+	r.Register("influx", mockInflux)
+
+	// Module.
+	mockRedis.EXPECT().HealthCheck(context.Background(), "").Return(okReport, nil).Times(1)
+	var jsonReport, err = r.HealthCheck(context.Background(), "redis")
+	assert.Nil(t, err)
+
+	var report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "OK", report.Status)
+	assert.Len(t, report.Modules, 1)
+	assert.Contains(t, report.Modules, "redis")
+
+	// Module and check.
+	mockInflux.EXPECT().HealthCheck(context.Background(), "ping").Return(koReport, nil).Times(1)
+	jsonReport, err = r.HealthCheck(context.Background(), "influx/ping")
+	assert.Nil(t, err)
+
+	report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "KO", report.Status)
+	assert.Len(t, report.Modules, 1)
+	assert.Equal(t, "KO", report.Modules["influx"][0].Status)
+
+	// Error from the module is returned as is.
+	var hcErr = fmt.Errorf("fail")
+	mockInflux.EXPECT().HealthCheck(context.Background(), "ping").Return(nil, hcErr).Times(1)
+	jsonReport, err = r.HealthCheck(context.Background(), "influx/ping")
+	assert.Equal(t, hcErr, err)
+	assert.Nil(t, jsonReport)
+
+	// Except the invalid names, that are named with the module.
+	mockInflux.EXPECT().HealthCheck(context.Background(), "unknown").Return(nil, &ErrInvalidHCName{}).Times(1)
+	jsonReport, err = r.HealthCheck(context.Background(), "influx/unknown")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Equal(t, "no health check with name 'influx/unknown'", err.Error())
+	assert.Nil(t, jsonReport)
+}
+
+func TestRegistryUnknownModule(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mock.NewRedisClient(mockCtrl), true))
+
+	var jsonReport, err = r.HealthCheck(context.Background(), "unknown/ping")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
+
+	// The unknown checks of a module are named with the module.
+	jsonReport, err = r.HealthCheck(context.Background(), "redis/nope")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Equal(t, "no health check with name 'redis/nope'", err.Error())
+	assert.Nil(t, jsonReport)
+}
+
+func TestRegistryWithModules(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewCockroachClient(mockCtrl)
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("cockroach", NewCockroachModule(mockCockroach, true))
+	r.Register("redis", NewRedisModule(mockRedis, false))
+
+	mockCockroach.EXPECT().Ping().Return(nil).Times(1)
+	var jsonReport, err = r.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var report = registryReport{}
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, "OK", report.Status)
+	assert.Equal(t, "ping", report.Modules["cockroach"][0].Name)
+	assert.Equal(t, "Deactivated", report.Modules["redis"][0].Status)
+}
+
+func TestRegistryCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewCockroachClient(mockCtrl)
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var r = NewRegistry()
+	r.Register("cockroach", NewCockroachModule(mockCockroach, true))
+	r.Register("influx", mockHealthChecker)
+
+	mockCockroach.EXPECT().Ping().Return(nil).Times(1)
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(koReport, nil).Times(1)
+	var results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	// Results are in registration order, with their module set.
+	assert.Equal(t, "cockroach", results[0].Module)
+	assert.Equal(t, OK, results[0].Status)
+	assert.Equal(t, "influx", results[1].Module)
+	assert.Equal(t, KO, results[1].Status)
+	assert.Equal(t, time.Millisecond, results[1].Duration)
+	assert.Equal(t, KO, AggregateStatus(results))
+}
+
+func TestRegistryParallelism(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		r                   = NewRegistry()
+		modules             = []string{"cockroach1", "cockroach2", "cockroach3"}
+		running, maxRunning int32
+	)
+
+	for _, name := range modules {
+		var mockCockroach = mock.NewCockroachClient(mockCtrl)
+		mockCockroach.EXPECT().Ping().DoAndReturn(func() error {
+			var n = atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				var m = atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}).Times(2)
+		r.Register(name, NewCockroachModule(mockCockroach, true))
+	}
+
+	// Sequential.
+	var results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+
+	// Concurrent. The order of the results is the registration order.
+	r.SetMaxParallelism(2)
+	results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+	assert.Len(t, results, 3)
+	for i, name := range modules {
+		assert.Equal(t, name, results[i].Module)
+		assert.Equal(t, OK, results[i].Status)
+	}
+}
+
+func TestRegistryParallelismShared(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		r                   = NewRegistry()
+		running, maxRunning int32
+	)
+
+	// 4 modules of 3 checks.
+	for _, name := range []string{"redis1", "redis2", "redis3", "redis4"} {
+		var mockRedis = mock.NewRedisClient(mockCtrl)
+		mockRedis.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+			var n = atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				var m = atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil, fmt.Errorf("fail")
+		}).AnyTimes()
+		r.Register(name, NewRedisModule(mockRedis, true, WithRedisInfoCheck(RedisInfoThresholds{}), WithRedisClusterCheck()))
+	}
+
+	// The maximum applies to the modules and to their checks together.
+	r.SetMaxParallelism(2)
+	var results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 12)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+
+	atomic.StoreInt32(&maxRunning, 0)
+	r.SetMaxParallelism(12)
+	_, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.True(t, atomic.LoadInt32(&maxRunning) > 2)
+	assert.True(t, atomic.LoadInt32(&maxRunning) <= 12)
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockCockroach = mock.NewCockroachClient(mockCtrl)
+	var mockRedis = mock.NewHealthChecker(mockCtrl)
+	var unblock = make(chan struct{})
+	defer close(unblock)
+
+	var r = NewRegistry()
+	r.Register("cockroach", NewCockroachModule(mockCockroach, true))
+	r.Register("redis", mockRedis)
+	r.SetMaxParallelism(2)
+	r.SetTimeout(20 * time.Millisecond)
+
+	// The cockroach client and the redis module do not honour the context.
+	mockCockroach.EXPECT().Ping().DoAndReturn(func() error {
+		<-unblock
+		return nil
+	}).Times(1)
+	mockRedis.EXPECT().HealthCheck(gomock.Any(), "").DoAndReturn(func(context.Context, string) (json.RawMessage, error) {
+		<-unblock
+		return okReport, nil
+	}).Times(1)
+
+	var start = time.Now()
+	var results, err = r.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, "cockroach", results[0].Module)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Equal(t, ErrTimeout.Error(), results[0].Error)
+
+	assert.Equal(t, "redis", results[1].Module)
+	assert.Equal(t, "redis", results[1].Name)
+	assert.Equal(t, KO, results[1].Status)
+	assert.Equal(t, ErrTimeout.Error(), results[1].Error)
+}
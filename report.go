@@ -0,0 +1,319 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckResult is the result of a single health check.
+type CheckResult struct {
+	// Module is the name under which the module is registered in the Registry. It is set only in the
+	// results returned by a Registry and is not part of the JSON report, where the results are keyed by module.
+	Module   string
+	Name     string
+	Status   Status
+	Duration time.Duration
+	Error    string
+	Start    time.Time
+	End      time.Time
+	Details  map[string]interface{}
+}
+
+type checkResultJSON struct {
+	Name     string                 `json:"name"`
+	Status   Status                 `json:"status"`
+	Duration string                 `json:"duration,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Start    time.Time              `json:"start,omitzero"`
+	End      time.Time              `json:"end,omitzero"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The duration is encoded as a string, e.g. "1.5ms".
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	var duration string
+	if !r.Start.IsZero() || r.Duration != 0 {
+		duration = r.Duration.String()
+	}
+
+	return json.Marshal(checkResultJSON{
+		Name:     r.Name,
+		Status:   r.Status,
+		Duration: duration,
+		Error:    r.Error,
+		Start:    r.Start,
+		End:      r.End,
+		Details:  r.Details,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *CheckResult) UnmarshalJSON(data []byte) error {
+	var v checkResultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var duration time.Duration
+	if v.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(v.Duration)
+		if err != nil {
+			return err
+		}
+	}
+
+	*r = CheckResult{
+		Module:   r.Module,
+		Name:     v.Name,
+		Status:   v.Status,
+		Duration: duration,
+		Error:    v.Error,
+		Start:    v.Start,
+		End:      v.End,
+		Details:  v.Details,
+	}
+	return nil
+}
+
+// Report is the health report of a Registry: the overall status and the results of the checks, keyed by
+// module name.
+type Report struct {
+	Status  Status                   `json:"status"`
+	Modules map[string][]CheckResult `json:"modules"`
+}
+
+// NewReport returns the report of the results, which must have their Module set.
+func NewReport(results []CheckResult) Report {
+	var report = Report{
+		Status:  AggregateStatus(results),
+		Modules: map[string][]CheckResult{},
+	}
+	for _, r := range results {
+		report.Modules[r.Module] = append(report.Modules[r.Module], r)
+	}
+	return report
+}
+
+// Results returns the results of all modules, sorted by module name, with their Module set.
+func (r Report) Results() []CheckResult {
+	var names = make([]string, 0, len(r.Modules))
+	for name := range r.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []CheckResult
+	for _, name := range names {
+		for _, res := range r.Modules[name] {
+			res.Module = name
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// AggregateStatus returns the overall status of the results: KO if any check is KO, Degraded if any check
+// is Degraded, OK otherwise. Deactivated checks do not affect the overall status.
+func AggregateStatus(results []CheckResult) Status {
+	var status = OK
+	for _, r := range results {
+		switch r.Status {
+		case KO:
+			return KO
+		case Degraded:
+			status = Degraded
+		}
+	}
+	return status
+}
+
+// degradedError is the error of a check that works, but not as expected.
+type degradedError struct {
+	error
+}
+
+// degraded marks the error of a check function so that the check is reported Degraded instead of KO.
+func degraded(err error) error {
+	return &degradedError{err}
+}
+
+// runCheck executes the health check f and returns its result. The check is KO if f returns an error, or
+// if the context is done before f returns, in which case the error is ErrTimeout or ErrCanceled. It is
+// Degraded if the error is marked with degraded.
+func runCheck(ctx context.Context, name string, f func(context.Context) error) CheckResult {
+	var start = time.Now()
+	var err = runWithContext(ctx, f)
+	var end = time.Now()
+
+	var status = OK
+	if _, ok := errors.Cause(err).(*degradedError); ok {
+		status = Degraded
+	} else if err != nil {
+		status = KO
+	}
+
+	return CheckResult{
+		Name:     name,
+		Status:   status,
+		Duration: end.Sub(start),
+		Error:    str(err),
+		Start:    start,
+		End:      end,
+	}
+}
+
+// runDetailedCheck is like runCheck, for the health checks that report details.
+func runDetailedCheck(ctx context.Context, name string, f func(context.Context) (map[string]interface{}, error)) CheckResult {
+	var mutex sync.Mutex
+	var details map[string]interface{}
+
+	var r = runCheck(ctx, name, func(ctx context.Context) error {
+		var d, err = f(ctx)
+		mutex.Lock()
+		details = d
+		mutex.Unlock()
+		return err
+	})
+
+	mutex.Lock()
+	r.Details = details
+	mutex.Unlock()
+	return r
+}
+
+// runNamedChecks executes the check of the given name among the enabled names, or all the enabled checks
+// for the name "". The checks are keyed by name. An unknown or disabled name is an ErrInvalidHCName.
+func runNamedChecks(ctx context.Context, name string, names []string, checks map[string]func(context.Context) CheckResult) ([]CheckResult, error) {
+	if name == "" {
+		var all []func(context.Context) CheckResult
+		for _, n := range names {
+			all = append(all, checks[n])
+		}
+		return runChecks(ctx, all...), nil
+	}
+
+	for _, n := range names {
+		if n == name {
+			return []CheckResult{checks[n](ctx)}, nil
+		}
+	}
+	return nil, &ErrInvalidHCName{name}
+}
+
+// runWithContext executes f and returns its error. If the context is done before f returns, it does not
+// wait for f and returns the context error.
+func runWithContext(ctx context.Context, f func(context.Context) error) error {
+	if ctx.Done() == nil {
+		return f(ctx)
+	}
+
+	var errc = make(chan error, 1)
+	go func() {
+		errc <- f(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil && ctx.Err() != nil {
+			return contextError(ctx)
+		}
+		return err
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+}
+
+// contextError returns ErrTimeout or ErrCanceled depending on the context error.
+func contextError(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	default:
+		return ErrCanceled
+	}
+}
+
+// deactivated returns the report of a deactivated module.
+func deactivated(module string) []CheckResult {
+	return []CheckResult{{Name: module, Status: Deactivated}}
+}
+
+// marshalResults returns the JSON report of a module.
+func marshalResults(results []CheckResult, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(results, "", "  ")
+}
+
+// decodeReport decodes a module report, i.e. a JSON array of results, or a Registry report. The results of
+// a Registry report have their Module set.
+func decodeReport(report json.RawMessage) ([]CheckResult, error) {
+	if isRegistryReport(report) {
+		var rr Report
+		if err := json.Unmarshal(report, &rr); err != nil {
+			return nil, err
+		}
+		return rr.Results(), nil
+	}
+
+	var results []CheckResult
+	if err := json.Unmarshal(report, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// encodeReport encodes the results like the original report, i.e. as a Registry report if the original
+// report is one, as a module report otherwise.
+func encodeReport(original json.RawMessage, results []CheckResult) (json.RawMessage, error) {
+	if isRegistryReport(original) {
+		return json.MarshalIndent(NewReport(results), "", "  ")
+	}
+	return json.MarshalIndent(results, "", "  ")
+}
+
+func isRegistryReport(report json.RawMessage) bool {
+	var r = bytes.TrimSpace(report)
+	return len(r) > 0 && r[0] == '{'
+}
+
+// reportStatus returns the overall status of a module or Registry report, KO if the report cannot be
+// decoded.
+func reportStatus(report json.RawMessage) Status {
+	if isRegistryReport(report) {
+		var rr Report
+		if err := json.Unmarshal(report, &rr); err != nil {
+			return KO
+		}
+		return rr.Status
+	}
+
+	var results, err = decodeReport(report)
+	if err != nil {
+		return KO
+	}
+	return AggregateStatus(results)
+}
+
+// check executes the health check, using the typed API when the health checker provides it.
+func check(ctx context.Context, hc HealthChecker, name string) ([]CheckResult, error) {
+	if c, ok := hc.(Checker); ok {
+		return c.Check(ctx, name)
+	}
+
+	var report, err = hc.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReport(report)
+}
@@ -0,0 +1,105 @@
+package common_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusText(t *testing.T) {
+	for _, s := range []Status{OK, KO, Deactivated, Degraded} {
+		var text, err = s.MarshalText()
+		assert.Nil(t, err)
+		assert.Equal(t, s.String(), string(text))
+
+		var decoded Status
+		assert.Nil(t, decoded.UnmarshalText(text))
+		assert.Equal(t, s, decoded)
+	}
+
+	var s Status
+	assert.NotNil(t, s.UnmarshalText([]byte("unknown")))
+}
+
+func TestCheckResultJSON(t *testing.T) {
+	var (
+		start  = time.Date(2018, time.March, 1, 12, 0, 0, 0, time.UTC)
+		result = CheckResult{
+			Module:   "redis",
+			Name:     "ping",
+			Status:   KO,
+			Duration: 1500 * time.Microsecond,
+			Error:    "fail",
+			Start:    start,
+			End:      start.Add(1500 * time.Microsecond),
+			Details:  map[string]interface{}{"role": "master"},
+		}
+	)
+
+	var data, err = json.Marshal(result)
+	assert.Nil(t, err)
+
+	// Check the JSON shape.
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "ping", m["name"])
+	assert.Equal(t, "KO", m["status"])
+	assert.Equal(t, "1.5ms", m["duration"])
+	assert.Equal(t, "fail", m["error"])
+	assert.Equal(t, "2018-03-01T12:00:00Z", m["start"])
+	assert.NotContains(t, m, "module")
+
+	// Round trip. The module is not part of the JSON.
+	var decoded CheckResult
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	result.Module = ""
+	assert.Equal(t, result, decoded)
+
+	// Zero values are omitted.
+	data, err = json.Marshal(CheckResult{Name: "redis", Status: Deactivated})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"name": "redis", "status": "Deactivated"}`, string(data))
+}
+
+func TestReport(t *testing.T) {
+	var results = []CheckResult{
+		{Module: "redis", Name: "ping", Status: OK},
+		{Module: "influx", Name: "ping", Status: Deactivated},
+		{Module: "redis", Name: "write", Status: KO},
+	}
+
+	var r = NewReport(results)
+	assert.Equal(t, KO, r.Status)
+	assert.Len(t, r.Modules, 2)
+	assert.Len(t, r.Modules["redis"], 2)
+
+	// Results are sorted by module.
+	var flat = r.Results()
+	assert.Equal(t, []CheckResult{results[1], results[0], results[2]}, flat)
+}
+
+func TestAggregateStatus(t *testing.T) {
+	var tsts = []struct {
+		statuses []Status
+		expected Status
+	}{
+		{nil, OK},
+		{[]Status{OK, OK}, OK},
+		{[]Status{OK, Deactivated}, OK},
+		{[]Status{Deactivated}, OK},
+		{[]Status{OK, KO, Deactivated}, KO},
+		{[]Status{OK, Degraded, Deactivated}, Degraded},
+		{[]Status{Degraded, KO}, KO},
+	}
+
+	for _, tst := range tsts {
+		var results []CheckResult
+		for _, s := range tst.statuses {
+			results = append(results, CheckResult{Status: s})
+		}
+		assert.Equal(t, tst.expected, AggregateStatus(results))
+	}
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"regexp"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 )
@@ -33,56 +32,49 @@ type SentryClient interface {
 	URL() string
 }
 
-type sentryReport struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Duration string `json:"duration,omitempty"`
-	Error    string `json:"error,omitempty"`
+// HealthCheckNames returns the names of the sentry health checks.
+func (m *SentryModule) HealthCheckNames() []string {
+	return []string{"ping"}
 }
 
-// HealthCheck executes the desired influx health check.
-func (m *SentryModule) HealthCheck(_ context.Context, name string) (json.RawMessage, error) {
+// HealthCheck executes the desired sentry health check.
+func (m *SentryModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired sentry health check and returns its results.
+func (m *SentryModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
 	if !m.enabled {
-		return json.MarshalIndent([]influxReport{{Name: "sentry", Status: Deactivated.String()}}, "", "  ")
+		return deactivated("sentry"), nil
 	}
 
-	var reports []sentryReport
+	var results []CheckResult
 	switch name {
 	case "":
-		reports = append(reports, m.sentryPing())
+		results = runChecks(ctx, m.sentryPing)
 	case "ping":
-		reports = append(reports, m.sentryPing())
+		results = append(results, m.sentryPing(ctx))
 	default:
-		// Should not happen: there is a middleware validating the inputs name.
-		panic(fmt.Sprintf("Unknown sentry health check name: %v", name))
+		return nil, &ErrInvalidHCName{name}
 	}
 
-	return json.MarshalIndent(reports, "", "  ")
+	return results, nil
 }
 
-func (m *SentryModule) sentryPing() sentryReport {
-	var name = "ping"
-	var status = OK
-
-	// Get Sentry health status.
-	var now = time.Now()
-	var err = m.getSentryHealth()
-	var duration = time.Since(now)
-
-	if err != nil {
-		status = KO
-		err = errors.Wrap(err, "could not ping sentry")
-	}
-
-	return sentryReport{
-		Name:     name,
-		Duration: duration.String(),
-		Status:   status.String(),
-		Error:    str(err),
-	}
+func (m *SentryModule) sentryPing(ctx context.Context) CheckResult {
+	return runCheck(ctx, "ping", func(ctx context.Context) error {
+		// Get Sentry health status.
+		if err := m.getSentryHealth(ctx); err != nil {
+			return errors.Wrap(err, "could not ping sentry")
+		}
+		return nil
+	})
 }
 
-func (m *SentryModule) getSentryHealth() error {
+// sentryHealthBody is the body returned by the sentry health endpoint when there is no issue.
+var sentryHealthBody = regexp.MustCompile(`^ok$`)
+
+func (m *SentryModule) getSentryHealth(ctx context.Context) error {
 	// Build sentry health url from sentry dsn. The health url is <sentryURL>/_health
 	var dsn = m.sentry.URL()
 
@@ -91,37 +83,10 @@ func (m *SentryModule) getSentryHealth() error {
 		url = fmt.Sprintf("%s/_health", dsn[:idx])
 	}
 
-	// Query sentry health endpoint.
-	var res *http.Response
-	{
-		var err error
-		res, err = m.httpClient.Get(url)
-		if err != nil {
-			return err
-		}
-		if res != nil {
-			defer res.Body.Close()
-		}
-	}
-
-	// Chesk response status.
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("http response status code: %v", res.Status)
-	}
-
-	// Chesk response body. The sentry health endpoint returns "ok" when there is no issue.
-	var response []byte
-	{
-		var err error
-		response, err = ioutil.ReadAll(res.Body)
-		if err != nil {
-			return err
-		}
+	var health = HTTPCheck{
+		URL:            url,
+		ExpectedStatus: []int{http.StatusOK},
+		BodyRegexp:     sentryHealthBody,
 	}
-
-	if strings.Compare(string(response), "ok") == 0 {
-		return nil
-	}
-
-	return fmt.Errorf("response should be 'ok' but is: %v", string(response))
+	return health.execute(ctx, m.httpClient)
 }
@@ -172,8 +172,7 @@ func TestSentryUnkownHealthCheck(t *testing.T) {
 		healthCheckName = "unknown"
 	)
 
-	var f = func() {
-		m.HealthCheck(context.Background(), healthCheckName)
-	}
-	assert.Panics(t, f)
+	var jsonReport, err = m.HealthCheck(context.Background(), healthCheckName)
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+	assert.Nil(t, jsonReport)
 }
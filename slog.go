@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NewSlogLogger returns a go-kit logger writing to the slog logger, e.g. to use the logging middleware
+// with log/slog. The go-kit "level" and "msg" keys are mapped to the slog level and message, the other key
+// values to attributes.
+func NewSlogLogger(logger *slog.Logger) log.Logger {
+	return &slogLogger{
+		logger: logger,
+	}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) Log(keyvals ...interface{}) error {
+	var lvl = slog.LevelInfo
+	var msg string
+	var attrs []slog.Attr
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var key = fmt.Sprint(keyvals[i])
+		var value interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		switch key {
+		case "level":
+			lvl = slogLevel(fmt.Sprint(value))
+		case "msg":
+			msg = fmt.Sprint(value)
+		default:
+			attrs = append(attrs, slog.Any(key, value))
+		}
+	}
+
+	l.logger.LogAttrs(context.Background(), lvl, msg, attrs...)
+	return nil
+}
+
+func slogLevel(lvl string) slog.Level {
+	switch lvl {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
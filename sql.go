@@ -0,0 +1,245 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SQLClient is the interface of the SQL database, e.g. *sql.DB.
+type SQLClient interface {
+	PingContext(ctx context.Context) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	Stats() sql.DBStats
+}
+
+// SQLPlaceholder returns the placeholder of the nth (starting at 1) query argument.
+type SQLPlaceholder func(n int) string
+
+var (
+	// DollarPlaceholder is the placeholder of Postgres and Cockroach, e.g. $1.
+	DollarPlaceholder SQLPlaceholder = func(n int) string { return "$" + strconv.Itoa(n) }
+	// QuestionPlaceholder is the placeholder of MySQL, i.e. ?.
+	QuestionPlaceholder SQLPlaceholder = func(int) string { return "?" }
+)
+
+// SQLPoolThresholds are the thresholds of the connection pool check, from which the check is Degraded. Zero
+// disables a threshold.
+type SQLPoolThresholds struct {
+	// MaxOpen is the maximum number of open connections.
+	MaxOpen int
+	// MaxInUse is the maximum number of connections in use.
+	MaxInUse int
+	// MaxWaitCount is the maximum number of connections waited for since the previous check.
+	MaxWaitCount int64
+}
+
+// SQLOption is an option of the SQL module.
+type SQLOption func(*SQLModule)
+
+// WithValidationQuery enables the "query" check, executing the validation query, e.g. "SELECT 1".
+func WithValidationQuery(query string) SQLOption {
+	return func(m *SQLModule) {
+		m.validationQuery = query
+	}
+}
+
+// WithHealthTable enables the "write" check, that inserts, reads back and deletes a row in the health
+// table. The table must have a string "id" primary key and a string "value" column. A nil placeholder is
+// the QuestionPlaceholder.
+func WithHealthTable(table string, placeholder SQLPlaceholder) SQLOption {
+	return func(m *SQLModule) {
+		if placeholder == nil {
+			placeholder = QuestionPlaceholder
+		}
+		m.healthTable = table
+		m.placeholder = placeholder
+	}
+}
+
+// WithPoolThresholds sets the thresholds of the "pool" check.
+func WithPoolThresholds(thresholds SQLPoolThresholds) SQLOption {
+	return func(m *SQLModule) {
+		m.poolThresholds = thresholds
+	}
+}
+
+// NewSQLModule returns the SQL health module. The "ping" and "pool" checks are always executed, the "query"
+// and "write" checks when they are enabled by the options.
+func NewSQLModule(db SQLClient, enabled bool, options ...SQLOption) *SQLModule {
+	var m = &SQLModule{
+		db:      db,
+		enabled: enabled,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// SQLModule is the health check module for SQL databases.
+type SQLModule struct {
+	db              SQLClient
+	enabled         bool
+	validationQuery string
+	healthTable     string
+	placeholder     SQLPlaceholder
+	poolThresholds  SQLPoolThresholds
+
+	mutex             sync.Mutex
+	lastWaitCount     int64
+	waitCountObserved bool
+}
+
+// HealthCheckNames returns the names of the SQL health checks.
+func (m *SQLModule) HealthCheckNames() []string {
+	var names = []string{"ping"}
+	if m.validationQuery != "" {
+		names = append(names, "query")
+	}
+	if m.healthTable != "" {
+		names = append(names, "write")
+	}
+	return append(names, "pool")
+}
+
+// HealthCheck executes the desired SQL health check.
+func (m *SQLModule) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	return marshalResults(m.Check(ctx, name))
+}
+
+// Check executes the desired SQL health check and returns its results.
+func (m *SQLModule) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	if !m.enabled {
+		return deactivated("sql"), nil
+	}
+
+	var checks = map[string]func(context.Context) CheckResult{
+		"ping":  m.sqlPing,
+		"query": m.sqlQuery,
+		"write": m.sqlWrite,
+		"pool":  m.sqlPool,
+	}
+
+	return runNamedChecks(ctx, name, m.HealthCheckNames(), checks)
+}
+
+func (m *SQLModule) sqlPing(ctx context.Context) CheckResult {
+	return runCheck(ctx, "ping", func(ctx context.Context) error {
+		if err := m.db.PingContext(ctx); err != nil {
+			return errors.Wrap(err, "could not ping database")
+		}
+		return nil
+	})
+}
+
+func (m *SQLModule) sqlQuery(ctx context.Context) CheckResult {
+	return runCheck(ctx, "query", func(ctx context.Context) error {
+		var rows, err = m.db.QueryContext(ctx, m.validationQuery)
+		if err != nil {
+			return errors.Wrap(err, "could not execute validation query")
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+		}
+		if err = rows.Err(); err != nil {
+			return errors.Wrap(err, "could not read validation query results")
+		}
+		return nil
+	})
+}
+
+func (m *SQLModule) sqlWrite(ctx context.Context) CheckResult {
+	return runCheck(ctx, "write", func(ctx context.Context) error {
+		var (
+			id     = fmt.Sprintf("healthcheck-%d", time.Now().UnixNano())
+			value  = strconv.FormatInt(time.Now().UnixNano(), 10)
+			insert = fmt.Sprintf("INSERT INTO %s (id, value) VALUES (%s, %s)", m.healthTable, m.placeholder(1), m.placeholder(2))
+			query  = fmt.Sprintf("SELECT value FROM %s WHERE id = %s", m.healthTable, m.placeholder(1))
+			remove = fmt.Sprintf("DELETE FROM %s WHERE id = %s", m.healthTable, m.placeholder(1))
+		)
+
+		if _, err := m.db.ExecContext(ctx, insert, id, value); err != nil {
+			return errors.Wrap(err, "write failed")
+		}
+
+		// The row is deleted even if it cannot be read back, so that the health table does not grow.
+		var read, readErr = m.readValue(ctx, query, id)
+		var _, deleteErr = m.db.ExecContext(ctx, remove, id)
+
+		switch {
+		case readErr != nil:
+			return errors.Wrap(readErr, "read failed")
+		case read != value:
+			return errors.Errorf("read failed: value should be '%s' but is '%s'", value, read)
+		case deleteErr != nil:
+			return errors.Wrap(deleteErr, "delete failed")
+		}
+		return nil
+	})
+}
+
+func (m *SQLModule) readValue(ctx context.Context, query string, id string) (string, error) {
+	var rows, err = m.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("row not found")
+	}
+
+	var value string
+	if err = rows.Scan(&value); err != nil {
+		return "", err
+	}
+	return value, rows.Err()
+}
+
+func (m *SQLModule) sqlPool(ctx context.Context) CheckResult {
+	return runDetailedCheck(ctx, "pool", func(ctx context.Context) (map[string]interface{}, error) {
+		var stats = m.db.Stats()
+
+		// The first observation is the baseline of the wait count, not counted against the threshold.
+		m.mutex.Lock()
+		var waitCount int64
+		if m.waitCountObserved {
+			waitCount = stats.WaitCount - m.lastWaitCount
+		}
+		m.lastWaitCount = stats.WaitCount
+		m.waitCountObserved = true
+		m.mutex.Unlock()
+
+		var details = map[string]interface{}{
+			"max_open":      stats.MaxOpenConnections,
+			"open":          stats.OpenConnections,
+			"in_use":        stats.InUse,
+			"idle":          stats.Idle,
+			"wait_count":    stats.WaitCount,
+			"wait_duration": stats.WaitDuration.String(),
+		}
+
+		var t = m.poolThresholds
+		switch {
+		case t.MaxOpen > 0 && stats.OpenConnections > t.MaxOpen:
+			return details, degraded(errors.Errorf("%d open connections, more than %d", stats.OpenConnections, t.MaxOpen))
+		case t.MaxInUse > 0 && stats.InUse > t.MaxInUse:
+			return details, degraded(errors.Errorf("%d connections in use, more than %d", stats.InUse, t.MaxInUse))
+		case t.MaxWaitCount > 0 && waitCount > t.MaxWaitCount:
+			return details, degraded(errors.Errorf("waited for %d connections since the previous check, more than %d", waitCount, t.MaxWaitCount))
+		}
+		return details, nil
+	})
+}
@@ -0,0 +1,336 @@
+package common_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDB is an in-process stand-in of a database, served by the "fakesql" driver. The queries are
+// answered by its functions.
+type fakeDB struct {
+	ping  func() error
+	exec  func(query string, args []driver.Value) error
+	query func(query string, args []driver.Value) (columns []string, rows [][]driver.Value, err error)
+}
+
+var (
+	fakeDBsMutex sync.Mutex
+	fakeDBs      = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("fakesql", fakeDriver{})
+}
+
+// openFakeDB returns a *sql.DB connected to the fake database.
+func openFakeDB(t *testing.T, db *fakeDB) *sql.DB {
+	fakeDBsMutex.Lock()
+	var name = fmt.Sprintf("%s-%d", t.Name(), len(fakeDBs))
+	fakeDBs[name] = db
+	fakeDBsMutex.Unlock()
+
+	var conn, err = sql.Open("fakesql", name)
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMutex.Lock()
+	defer fakeDBsMutex.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("transactions not supported") }
+
+func (c *fakeConn) Ping(context.Context) error {
+	if c.db.ping == nil {
+		return nil
+	}
+	return c.db.ping()
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.db.exec == nil {
+		return nil, fmt.Errorf("unexpected exec: %s", s.query)
+	}
+	if err := s.db.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.db.query == nil {
+		return nil, fmt.Errorf("unexpected query: %s", s.query)
+	}
+	var columns, rows, err = s.db.query(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// healthTable is a fake health table.
+type healthTable struct {
+	mutex sync.Mutex
+	rows  map[string]string
+}
+
+func (h *healthTable) exec(query string, args []driver.Value) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO health (id, value) VALUES ($1, $2)"):
+		h.rows[args[0].(string)] = args[1].(string)
+	case strings.HasPrefix(query, "DELETE FROM health WHERE id = $1"):
+		delete(h.rows, args[0].(string))
+	default:
+		return fmt.Errorf("unexpected exec: %s", query)
+	}
+	return nil
+}
+
+func (h *healthTable) query(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	switch query {
+	case "SELECT 1":
+		return []string{"1"}, [][]driver.Value{{int64(1)}}, nil
+	case "SELECT value FROM health WHERE id = $1":
+		var value, ok = h.rows[args[0].(string)]
+		if !ok {
+			return []string{"value"}, nil, nil
+		}
+		return []string{"value"}, [][]driver.Value{{value}}, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func TestSQLModule(t *testing.T) {
+	var table = &healthTable{rows: map[string]string{}}
+	var db = openFakeDB(t, &fakeDB{exec: table.exec, query: table.query})
+
+	var m = NewSQLModule(db, true, WithValidationQuery("SELECT 1"), WithHealthTable("health", DollarPlaceholder))
+	assert.Equal(t, []string{"ping", "query", "write", "pool"}, m.HealthCheckNames())
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Len(t, results, 4)
+	for i, name := range []string{"ping", "query", "write", "pool"} {
+		assert.Equal(t, name, results[i].Name)
+		assert.Equal(t, OK, results[i].Status, results[i].Error)
+	}
+	assert.Empty(t, table.rows)
+	assert.Contains(t, results[3].Details, "in_use")
+	assert.Contains(t, results[3].Details, "wait_count")
+
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, OK, results[0].Status)
+
+	// A nil placeholder is the QuestionPlaceholder.
+	var questions = &fakeDB{
+		exec: func(query string, _ []driver.Value) error {
+			assert.NotContains(t, query, "$")
+			return nil
+		},
+		query: func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+			assert.Equal(t, "SELECT value FROM health WHERE id = ?", query)
+			return []string{"value"}, [][]driver.Value{{"other"}}, nil
+		},
+	}
+	results, err = NewSQLModule(openFakeDB(t, questions), true, WithHealthTable("health", nil)).Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Contains(t, results[0].Error, "read failed: value should be")
+
+	// The checks that are not enabled are unknown.
+	m = NewSQLModule(db, true)
+	assert.Equal(t, []string{"ping", "pool"}, m.HealthCheckNames())
+	_, err = m.Check(context.Background(), "write")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+
+	// Disabled.
+	m = NewSQLModule(db, false)
+	results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, Deactivated, results[0].Status)
+}
+
+func TestSQLModuleFailures(t *testing.T) {
+	var fake = &fakeDB{
+		ping: func() error { return fmt.Errorf("connection refused") },
+		exec: func(query string, _ []driver.Value) error {
+			if strings.HasPrefix(query, "INSERT") {
+				return fmt.Errorf("read-only")
+			}
+			return nil
+		},
+		query: func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+			return nil, nil, fmt.Errorf("syntax error")
+		},
+	}
+	var db = openFakeDB(t, fake)
+	var m = NewSQLModule(db, true, WithValidationQuery("SELECT"), WithHealthTable("health", QuestionPlaceholder))
+
+	var results, err = m.Check(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+	assert.Contains(t, results[0].Error, "could not ping database: connection refused")
+	assert.Equal(t, KO, results[1].Status)
+	assert.Contains(t, results[1].Error, "syntax error")
+	assert.Equal(t, KO, results[2].Status)
+	assert.Equal(t, "write failed: read-only", results[2].Error)
+
+	// Read failures. The row is deleted all the same.
+	var deleted int
+	fake.exec = func(query string, _ []driver.Value) error {
+		if strings.HasPrefix(query, "DELETE") {
+			deleted++
+		}
+		return nil
+	}
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Equal(t, "read failed: syntax error", results[0].Error)
+	assert.Equal(t, 1, deleted)
+
+	fake.query = func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"value"}, [][]driver.Value{{"other"}}, nil
+	}
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Contains(t, results[0].Error, "read failed: value should be")
+	assert.Equal(t, 2, deleted)
+
+	// Delete failure.
+	var table = &healthTable{rows: map[string]string{}}
+	fake.query = table.query
+	fake.exec = func(query string, args []driver.Value) error {
+		if strings.HasPrefix(query, "DELETE") {
+			return fmt.Errorf("permission denied")
+		}
+		return table.exec(query, args)
+	}
+	m = NewSQLModule(db, true, WithHealthTable("health", DollarPlaceholder))
+	results, err = m.Check(context.Background(), "write")
+	assert.Nil(t, err)
+	assert.Equal(t, "delete failed: permission denied", results[0].Error)
+}
+
+func TestSQLModulePool(t *testing.T) {
+	var db = openFakeDB(t, &fakeDB{})
+
+	// Connections in use.
+	var conn1, err = db.Conn(context.Background())
+	assert.Nil(t, err)
+	var conn2 *sql.Conn
+	conn2, err = db.Conn(context.Background())
+	assert.Nil(t, err)
+
+	var m = NewSQLModule(db, true)
+	var results []CheckResult
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+	assert.Equal(t, 2, results[0].Details["open"])
+	assert.Equal(t, 2, results[0].Details["in_use"])
+
+	m = NewSQLModule(db, true, WithPoolThresholds(SQLPoolThresholds{MaxOpen: 1}))
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Equal(t, "2 open connections, more than 1", results[0].Error)
+
+	m = NewSQLModule(db, true, WithPoolThresholds(SQLPoolThresholds{MaxOpen: 2, MaxInUse: 1}))
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Equal(t, "2 connections in use, more than 1", results[0].Error)
+
+	// The first check is the baseline of the connections waited for.
+	m = NewSQLModule(db, true, WithPoolThresholds(SQLPoolThresholds{MaxWaitCount: 1}))
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+
+	// Connections waited for.
+	db.SetMaxOpenConns(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var c, err = db.Conn(context.Background())
+			assert.Nil(t, err)
+			c.Close()
+		}()
+	}
+	assert.Eventually(t, func() bool { return db.Stats().WaitCount == 2 }, time.Second, time.Millisecond)
+	conn1.Close()
+	conn2.Close()
+	wg.Wait()
+
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Equal(t, "waited for 2 connections since the previous check, more than 1", results[0].Error)
+
+	// Only the connections waited for since the previous check count.
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+
+	// The connections waited for before the first check are not counted.
+	m = NewSQLModule(db, true, WithPoolThresholds(SQLPoolThresholds{MaxWaitCount: 1}))
+	results, err = m.Check(context.Background(), "pool")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+}
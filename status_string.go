@@ -4,9 +4,9 @@ package common
 
 import "strconv"
 
-const _Status_name = "OKKODeactivated"
+const _Status_name = "OKKODeactivatedDegraded"
 
-var _Status_index = [...]uint8{0, 2, 4, 15}
+var _Status_index = [...]uint8{0, 2, 4, 15, 23}
 
 func (i Status) String() string {
 	if i < 0 || i >= Status(len(_Status_index)-1) {
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MakeLatencyThresholdMW makes a middleware that downgrades the successful checks that took longer than
+// their threshold to Degraded. The thresholds are keyed by check name, e.g. "ping", or by module and check
+// name for the results of a Registry, e.g. "redis/ping". The latter takes precedence.
+func MakeLatencyThresholdMW(thresholds map[string]time.Duration) func(HealthChecker) HealthChecker {
+	return func(next HealthChecker) HealthChecker {
+		return &latencyThresholdMW{
+			thresholds: thresholds,
+			next:       next,
+		}
+	}
+}
+
+type latencyThresholdMW struct {
+	thresholds map[string]time.Duration
+	next       HealthChecker
+}
+
+// HealthCheck implements HealthChecker.
+func (m *latencyThresholdMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var report, err = m.next.HealthCheck(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CheckResult
+	results, err = decodeReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return encodeReport(report, m.apply(results))
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (m *latencyThresholdMW) HealthCheckNames() []string {
+	return healthCheckNames(m.next)
+}
+
+// Check implements Checker.
+func (m *latencyThresholdMW) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var results, err = check(ctx, m.next, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.apply(results), nil
+}
+
+func (m *latencyThresholdMW) apply(results []CheckResult) []CheckResult {
+	for i, r := range results {
+		if r.Status != OK {
+			continue
+		}
+
+		var threshold, ok = m.thresholds[r.Module+"/"+r.Name]
+		if !ok {
+			threshold, ok = m.thresholds[r.Name]
+		}
+		if ok && r.Duration > threshold {
+			results[i].Status = Degraded
+			results[i].Error = fmt.Sprintf("took %v, more than the threshold of %v", r.Duration, threshold)
+		}
+	}
+	return results
+}
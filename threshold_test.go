@@ -0,0 +1,78 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLatencyThresholdMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var (
+		thresholds = map[string]time.Duration{
+			"ping":       10 * time.Millisecond,
+			"redis/ping": time.Second,
+		}
+		m          = MakeLatencyThresholdMW(thresholds)(mockHealthChecker)
+		slowReport = json.RawMessage(`[{"name": "ping", "status": "OK", "duration": "500ms"}, {"name": "write", "status": "OK", "duration": "1h"}]`)
+		regReport  = json.RawMessage(`{"status": "OK", "modules": {"redis": [{"name": "ping", "status": "OK", "duration": "500ms"}], "influx": [{"name": "ping", "status": "OK", "duration": "500ms"}]}}`)
+	)
+
+	// Module report.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(slowReport, nil).Times(1)
+	var jsonReport, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var results []CheckResult
+	assert.Nil(t, json.Unmarshal(jsonReport, &results))
+	assert.Equal(t, Degraded, results[0].Status)
+	assert.Contains(t, results[0].Error, "threshold")
+	assert.Equal(t, OK, results[1].Status)
+	assert.Equal(t, Degraded, AggregateStatus(results))
+
+	// Registry report: the module specific threshold takes precedence.
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(regReport, nil).Times(1)
+	jsonReport, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+
+	var report Report
+	assert.Nil(t, json.Unmarshal(jsonReport, &report))
+	assert.Equal(t, Degraded, report.Status)
+	assert.Equal(t, OK, report.Modules["redis"][0].Status)
+	assert.Equal(t, Degraded, report.Modules["influx"][0].Status)
+}
+
+func TestLatencyThresholdMWCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = MakeLatencyThresholdMW(map[string]time.Duration{"ping": time.Millisecond})(NewRedisModule(mockRedis, true))
+
+	// Slow.
+	mockRedis.EXPECT().Do("PING").DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}).Times(1)
+	var results, err = m.(Checker).Check(context.Background(), "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, Degraded, results[0].Status)
+
+	// Failures are not downgraded.
+	mockRedis.EXPECT().Do("PING").DoAndReturn(func(string, ...interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, assert.AnError
+	}).Times(1)
+	results, err = m.(Checker).Check(context.Background(), "ping")
+	assert.Nil(t, err)
+	assert.Equal(t, KO, results[0].Status)
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MakeHealthCheckerTracingMW makes a tracing middleware for the health check modules. Each health check is
+// traced in a "HealthCheck" span, which is propagated through the context given to the modules, and each
+// executed check in a child span named after its module and check, e.g. "redis ping". The spans are tagged
+// with the status, the duration and the error of the checks.
+func MakeHealthCheckerTracingMW(tracer trace.Tracer) func(HealthChecker) HealthChecker {
+	return func(next HealthChecker) HealthChecker {
+		return &healthCheckerTracingMW{
+			tracer: tracer,
+			next:   next,
+		}
+	}
+}
+
+type healthCheckerTracingMW struct {
+	tracer trace.Tracer
+	next   HealthChecker
+}
+
+// HealthCheck implements HealthChecker.
+func (m *healthCheckerTracingMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
+	var ctx2, span = m.start(ctx, name)
+	defer span.End()
+
+	var report, err = m.next.HealthCheck(ctx2, name)
+	if err != nil {
+		m.fail(span, err)
+		return nil, err
+	}
+
+	if results, err := decodeReport(report); err == nil {
+		m.trace(ctx2, span, results)
+	}
+	return report, nil
+}
+
+// HealthCheckNames implements HealthCheckNamer, with the names of the underlying health checker.
+func (m *healthCheckerTracingMW) HealthCheckNames() []string {
+	return healthCheckNames(m.next)
+}
+
+// Check implements Checker.
+func (m *healthCheckerTracingMW) Check(ctx context.Context, name string) ([]CheckResult, error) {
+	var ctx2, span = m.start(ctx, name)
+	defer span.End()
+
+	var results, err = check(ctx2, m.next, name)
+	if err != nil {
+		m.fail(span, err)
+		return nil, err
+	}
+
+	m.trace(ctx2, span, results)
+	return results, nil
+}
+
+func (m *healthCheckerTracingMW) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, "HealthCheck", trace.WithAttributes(attribute.String("healthcheck.name", name)))
+}
+
+func (m *healthCheckerTracingMW) fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// trace records a child span per result. The checks are already executed, so the spans are given the
+// start and end times of the results.
+func (m *healthCheckerTracingMW) trace(ctx context.Context, span trace.Span, results []CheckResult) {
+	var status = AggregateStatus(results)
+	span.SetAttributes(attribute.String("healthcheck.status", status.String()))
+	if status == KO {
+		span.SetStatus(codes.Error, "health check failed")
+	}
+
+	for _, r := range results {
+		var start, end = r.Start, r.End
+		if start.IsZero() {
+			start = time.Now().Add(-r.Duration)
+			end = start.Add(r.Duration)
+		}
+
+		var spanName = r.Name
+		if r.Module != "" && r.Module != r.Name {
+			spanName = r.Module + " " + r.Name
+		}
+
+		var _, child = m.tracer.Start(ctx, spanName,
+			trace.WithTimestamp(start),
+			trace.WithAttributes(
+				attribute.String("healthcheck.module", r.Module),
+				attribute.String("healthcheck.check", r.Name),
+				attribute.String("healthcheck.status", r.Status.String()),
+				attribute.Float64("healthcheck.duration", r.Duration.Seconds()),
+			),
+		)
+		if r.Error != "" {
+			child.SetAttributes(attribute.String("healthcheck.error", r.Error))
+		}
+		if r.Status == KO {
+			child.RecordError(errors.New(r.Error), trace.WithTimestamp(end))
+			child.SetStatus(codes.Error, r.Error)
+		}
+		child.End(trace.WithTimestamp(end))
+	}
+}
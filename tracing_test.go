@@ -0,0 +1,140 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	. "github.com/cloudtrust/common-healthcheck"
+	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+)
+
+func newTracer() (trace.Tracer, *tracetest.InMemoryExporter) {
+	var exporter = tracetest.NewInMemoryExporter()
+	var provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return provider.Tracer("healthcheck"), exporter
+}
+
+func spanAttribute(s tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+	var mockInflux = mock.NewInfluxClient(mockCtrl)
+
+	var tracer, exporter = newTracer()
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	r.Register("influx", NewInfluxModule(mockInflux, false))
+	var m = MakeHealthCheckerTracingMW(tracer)(r)
+
+	mockRedis.EXPECT().Do("PING").Return(nil, fmt.Errorf("fail")).Times(1)
+
+	var report, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+	assert.NotNil(t, report)
+
+	// The child spans are ended before their parent.
+	var spans = exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	var parent = spans[2]
+	assert.Equal(t, "HealthCheck", parent.Name)
+	assert.Equal(t, "", spanAttribute(parent, "healthcheck.name").AsString())
+	assert.Equal(t, "KO", spanAttribute(parent, "healthcheck.status").AsString())
+	assert.Equal(t, codes.Error, parent.Status.Code)
+
+	var children = map[string]tracetest.SpanStub{}
+	for _, s := range spans[:2] {
+		assert.Equal(t, parent.SpanContext.SpanID(), s.Parent.SpanID())
+		children[s.Name] = s
+	}
+
+	var redis = children["redis ping"]
+	assert.Equal(t, "redis", spanAttribute(redis, "healthcheck.module").AsString())
+	assert.Equal(t, "ping", spanAttribute(redis, "healthcheck.check").AsString())
+	assert.Equal(t, "KO", spanAttribute(redis, "healthcheck.status").AsString())
+	assert.Equal(t, "could not ping redis: fail", spanAttribute(redis, "healthcheck.error").AsString())
+	assert.Equal(t, attribute.FLOAT64, spanAttribute(redis, "healthcheck.duration").Type())
+	assert.Equal(t, codes.Error, redis.Status.Code)
+	assert.Len(t, redis.Events, 1)
+
+	var influx = children["influx"]
+	assert.Equal(t, "Deactivated", spanAttribute(influx, "healthcheck.status").AsString())
+	assert.Equal(t, codes.Unset, influx.Status.Code)
+}
+
+func TestTracingMWContext(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+
+	var tracer, exporter = newTracer()
+	var m = MakeHealthCheckerTracingMW(tracer)(mockHealthChecker)
+
+	// The span is propagated to the health checks.
+	var ctx, span = tracer.Start(context.Background(), "request")
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "redis").DoAndReturn(func(ctx context.Context, _ string) (json.RawMessage, error) {
+		var s = trace.SpanFromContext(ctx)
+		assert.True(t, s.SpanContext().IsValid())
+		assert.Equal(t, span.SpanContext().TraceID(), s.SpanContext().TraceID())
+		assert.NotEqual(t, span.SpanContext().SpanID(), s.SpanContext().SpanID())
+		return okReport, nil
+	}).Times(1)
+	var _, err = m.HealthCheck(ctx, "redis")
+	assert.Nil(t, err)
+	span.End()
+
+	var spans = exporter.GetSpans()
+	assert.Equal(t, "HealthCheck", spans[len(spans)-2].Name)
+	assert.Equal(t, span.SpanContext().SpanID(), spans[len(spans)-2].Parent.SpanID())
+	assert.Equal(t, codes.Unset, spans[len(spans)-2].Status.Code)
+
+	// Errors.
+	exporter.Reset()
+	mockHealthChecker.EXPECT().HealthCheck(gomock.Any(), "unknown").Return(nil, &ErrInvalidHCName{}).Times(1)
+	_, err = m.HealthCheck(context.Background(), "unknown")
+	assert.NotNil(t, err)
+	spans = exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Len(t, spans[0].Events, 1)
+}
+
+func TestTracingMWCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var tracer, exporter = newTracer()
+	var r = NewRegistry()
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	var m = MakeHealthCheckerTracingMW(tracer)(r)
+
+	mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(1)
+	var results, err = m.(Checker).Check(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+	assert.Equal(t, OK, results[0].Status)
+
+	var spans = exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "redis ping", spans[0].Name)
+	assert.Equal(t, results[0].Start, spans[0].StartTime)
+	assert.Equal(t, results[0].End, spans[0].EndTime)
+	assert.Equal(t, "OK", spanAttribute(spans[1], "healthcheck.status").AsString())
+}
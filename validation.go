@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 )
 
 // MakeValidationMiddleware makes a middleware that validate the health check name comming from
@@ -21,14 +22,35 @@ import (
 func MakeValidationMiddleware(validValues map[string]struct{}) func(HealthChecker) HealthChecker {
 	return func(next HealthChecker) HealthChecker {
 		return &validationMW{
-			validValues: validValues,
+			validValues: func() map[string]struct{} { return validValues },
 			next:        next,
 		}
 	}
 }
 
+// MakeModuleValidationMiddleware makes a middleware that validates the health check name like
+// MakeValidationMiddleware, with the valid values derived from the health check names of the module it
+// wraps, plus "". If the module does not implement HealthCheckNamer, only "" is valid. The names are looked
+// up on each health check, so that the modules registered after wrapping a Registry are valid.
+func MakeModuleValidationMiddleware() func(HealthChecker) HealthChecker {
+	return func(next HealthChecker) HealthChecker {
+		return &validationMW{
+			validValues: func() map[string]struct{} {
+				var validValues = map[string]struct{}{
+					"": struct{}{},
+				}
+				for _, name := range healthCheckNames(next) {
+					validValues[name] = struct{}{}
+				}
+				return validValues
+			},
+			next: next,
+		}
+	}
+}
+
 type validationMW struct {
-	validValues map[string]struct{}
+	validValues func() map[string]struct{}
 	next        HealthChecker
 }
 
@@ -44,10 +66,31 @@ func (e *ErrInvalidHCName) Error() string {
 
 func (m *validationMW) HealthCheck(ctx context.Context, name string) (json.RawMessage, error) {
 	// Check health check name validity.
-	var _, ok = m.validValues[name]
+	var _, ok = m.validValues()[name]
 	if !ok {
 		return nil, &ErrInvalidHCName{name}
 	}
 
 	return m.next.HealthCheck(ctx, name)
 }
+
+// HealthCheckNames returns the valid health check names, except "".
+func (m *validationMW) HealthCheckNames() []string {
+	var names []string
+	for name := range m.validValues() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// healthCheckNames returns the health check names of the health checker, or nil if it does not implement
+// HealthCheckNamer. The middlewares forward them, so that the names of a module are known through them.
+func healthCheckNames(hc HealthChecker) []string {
+	if namer, ok := hc.(HealthCheckNamer); ok {
+		return namer.HealthCheckNames()
+	}
+	return nil
+}
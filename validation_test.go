@@ -9,7 +9,10 @@ import (
 
 	. "github.com/cloudtrust/common-healthcheck"
 	"github.com/cloudtrust/common-healthcheck/mock"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/mock/gomock"
 )
 
@@ -60,3 +63,128 @@ func TestValidationMW(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleValidationMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	var m = MakeModuleValidationMiddleware()(NewRedisModule(mockRedis, true))
+	assert.Equal(t, []string{"ping"}, m.(HealthCheckNamer).HealthCheckNames())
+
+	var tsts = []struct {
+		name    string
+		isValid bool
+	}{
+		{"", true},
+		{"ping", true},
+		{"write", false},
+		{"unknown", false},
+	}
+
+	for _, tst := range tsts {
+		if tst.isValid {
+			mockRedis.EXPECT().Do("PING").Return(nil, nil).Times(1)
+		}
+
+		var report, err = m.HealthCheck(context.Background(), tst.name)
+
+		if tst.isValid {
+			assert.Nil(t, err)
+			assert.NotNil(t, report)
+		} else {
+			assert.IsType(t, &ErrInvalidHCName{}, err)
+			assert.Nil(t, report)
+		}
+	}
+
+	// Without health check names, only "" is valid.
+	var mockHealthChecker = mock.NewHealthChecker(mockCtrl)
+	m = MakeModuleValidationMiddleware()(mockHealthChecker)
+	mockHealthChecker.EXPECT().HealthCheck(context.Background(), "").Return(json.RawMessage(`[]`), nil).Times(1)
+	var _, err = m.HealthCheck(context.Background(), "")
+	assert.Nil(t, err)
+	_, err = m.HealthCheck(context.Background(), "ping")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+}
+
+func TestHealthCheckNames(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var r = NewRegistry()
+	r.Register("cockroach", NewCockroachModule(mock.NewCockroachClient(mockCtrl), true))
+	r.Register("flaki", NewFlakiModule(mock.NewFlakiClient(mockCtrl), true))
+	r.Register("influx", NewInfluxModule(mock.NewInfluxClient(mockCtrl), true))
+	r.Register("jaeger", NewJaegerModule(nil, "", true))
+	r.Register("redis", NewRedisModule(mock.NewRedisClient(mockCtrl), true))
+	r.Register("sentry", NewSentryModule(mock.NewSentryClient(mockCtrl), nil, true))
+	r.Register("other", mock.NewHealthChecker(mockCtrl))
+
+	var expected = []string{
+		"cockroach", "cockroach/ping",
+		"flaki", "flaki/ping",
+		"influx", "influx/ping",
+		"jaeger", "jaeger/collector",
+		"redis", "redis/ping",
+		"sentry", "sentry/ping",
+		"other",
+	}
+	assert.Equal(t, expected, r.HealthCheckNames())
+
+	// The registry can be validated like any module.
+	var m = MakeModuleValidationMiddleware()(r)
+	var _, err = m.HealthCheck(context.Background(), "redis/write")
+	assert.IsType(t, &ErrInvalidHCName{}, err)
+}
+
+func TestMiddlewaresHealthCheckNames(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+	var module = NewRedisModule(mockRedis, true, WithRedisWriteCheck(time.Second))
+
+	var wrapped = map[string]HealthChecker{
+		"logging":       MakeHealthCheckerLoggingMW(log.NewNopLogger())(module),
+		"instrumenting": MakeHealthCheckerInstrumentingMW(generic.NewHistogram("duration", 10), generic.NewCounter("checks"), generic.NewGauge("status"))(module),
+		"tracing":       MakeHealthCheckerTracingMW(noop.NewTracerProvider().Tracer("test"))(module),
+		"threshold":     MakeLatencyThresholdMW(nil)(module),
+		"damping":       MakeFlapDampingMW(nil)(module),
+		"history":       NewHistory(module, 10),
+		"notifier":      NewNotifier(module, log.NewNopLogger()),
+		"cached":        NewCachedHealthChecker(module, time.Hour, time.Hour),
+	}
+
+	for name, hc := range wrapped {
+		// The names of the module are forwarded.
+		var namer, ok = hc.(HealthCheckNamer)
+		assert.True(t, ok, name)
+		assert.Equal(t, []string{"ping", "write"}, namer.HealthCheckNames(), name)
+
+		// The checks of the module are valid through the middleware.
+		mockRedis.EXPECT().Do("PING").Return("PONG", nil).Times(1)
+		var _, err = MakeModuleValidationMiddleware()(hc).HealthCheck(context.Background(), "ping")
+		assert.Nil(t, err, name)
+
+		// The checks of the module are listed by a Registry.
+		var r = NewRegistry()
+		r.Register("redis", hc)
+		assert.Equal(t, []string{"redis", "redis/ping", "redis/write"}, r.HealthCheckNames(), name)
+	}
+}
+
+func TestModuleValidationMWRegistry(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockRedis = mock.NewRedisClient(mockCtrl)
+
+	// The modules registered after the validation middleware wraps the registry are valid.
+	var r = NewRegistry()
+	var m = MakeModuleValidationMiddleware()(r)
+	r.Register("redis", NewRedisModule(mockRedis, true))
+	assert.Equal(t, []string{"redis", "redis/ping"}, m.(HealthCheckNamer).HealthCheckNames())
+
+	mockRedis.EXPECT().Do("PING").Return("PONG", nil).Times(1)
+	var _, err = m.HealthCheck(context.Background(), "redis/ping")
+	assert.Nil(t, err)
+}